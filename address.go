@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// An Addresser is a Container with its own network address, like a container in its own
+// network namespace. The gRPC probes, and the TCP and HTTP probes that do not specify a
+// host, target the first IP of the container. When Dialer returns a function, it is used
+// to open the connections of those probes from within the network namespace of the
+// container, otherwise they are opened from the host.
+type Addresser interface {
+	IPs() ([]net.IP, error)
+	Dialer() func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// addressedCheck returns a check that looks up the address of the container on every run,
+// since the container might only get it once it has started, and runs the check returned
+// by target against that address.
+func addressedCheck(addresser Addresser, port int, target func(address string) Check) Check {
+	return CheckFunc(func(ctx context.Context) (bool, error) {
+		ips, err := addresser.IPs()
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to get the address of the container")
+		} else if len(ips) == 0 {
+			return false, fmt.Errorf("container has no address")
+		}
+		address := net.JoinHostPort(ips[0].String(), strconv.Itoa(port))
+		return target(address).Run(ctx)
+	})
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// addressedContainer is a container with its own addresses, it counts the connections
+// opened through its dialer.
+type addressedContainer struct {
+	mockContainer
+
+	ips    []net.IP
+	err    error
+	netns  bool
+	lock   sync.Mutex
+	dialed []string
+}
+
+func (ctn *addressedContainer) IPs() ([]net.IP, error) { return ctn.ips, ctn.err }
+
+func (ctn *addressedContainer) Dialer() func(ctx context.Context, network, address string) (net.Conn, error) {
+	if !ctn.netns {
+		return nil
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		ctn.lock.Lock()
+		ctn.dialed = append(ctn.dialed, address)
+		ctn.lock.Unlock()
+		return (&net.Dialer{}).DialContext(ctx, network, address)
+	}
+}
+
+func (ctn *addressedContainer) Dialed() []string {
+	ctn.lock.Lock()
+	defer ctn.lock.Unlock()
+	return append([]string{}, ctn.dialed...)
+}
+
+func listenerPort(t *testing.T, addr net.Addr) int {
+	_, port, err := net.SplitHostPort(addr.String())
+	require.NoError(t, err)
+	n, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return n
+}
+
+func TestAddresser(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	port := listenerPort(t, listener.Addr())
+
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	httpPort := listenerPort(t, server.Listener.Addr())
+
+	t.Run("tcp_socket", func(t *testing.T) {
+		ctn := &addressedContainer{ips: []net.IP{net.IPv4(127, 0, 0, 1)}}
+		spec := NewProbeSpec()
+		spec.TCPSocket = &TCPSocketAction{Port: port}
+
+		check := spec.GetCheck(ctn)
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("tcp_socket_netns", func(t *testing.T) {
+		ctn := &addressedContainer{ips: []net.IP{net.IPv4(127, 0, 0, 1)}, netns: true}
+		spec := NewProbeSpec()
+		spec.TCPSocket = &TCPSocketAction{Port: port}
+
+		check := spec.GetCheck(ctn)
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+		require.Equal(t, []string{listener.Addr().String()}, ctn.Dialed())
+	})
+	t.Run("http_get_netns", func(t *testing.T) {
+		ctn := &addressedContainer{ips: []net.IP{net.IPv4(127, 0, 0, 1)}, netns: true}
+		spec := NewProbeSpec()
+		spec.HTTPGet = &HTTPGetAction{Path: "/", Port: httpPort}
+
+		check := spec.GetCheck(ctn)
+		for i := 0; i < 2; i++ {
+			success, err := check.Run(context.Background())
+			require.True(t, success)
+			require.NoError(t, err)
+		}
+		address := server.Listener.Addr().String()
+		require.Equal(t, []string{address, address}, ctn.Dialed())
+	})
+	t.Run("grpc_netns", func(t *testing.T) {
+		_, address, stop := startHealthServer(t)
+		defer stop()
+		_, grpcPort, err := net.SplitHostPort(address)
+		require.NoError(t, err)
+		ctn := &addressedContainer{ips: []net.IP{net.IPv4(127, 0, 0, 1)}, netns: true}
+		spec := NewProbeSpec()
+		spec.GRPC = &GRPCAction{}
+		spec.GRPC.Port, err = strconv.Atoi(grpcPort)
+		require.NoError(t, err)
+
+		check := spec.GetCheck(ctn)
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+		require.Equal(t, []string{address}, ctn.Dialed())
+	})
+	t.Run("explicit_host", func(t *testing.T) {
+		ctn := &addressedContainer{err: errors.New("no address"), netns: true}
+		spec := NewProbeSpec()
+		spec.HTTPGet = &HTTPGetAction{Host: "127.0.0.1", Path: "/", Port: httpPort}
+
+		check := spec.GetCheck(ctn)
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+		require.Empty(t, ctn.Dialed())
+	})
+	t.Run("address_error", func(t *testing.T) {
+		ctn := &addressedContainer{err: errors.New("container has no network yet")}
+		spec := NewProbeSpec()
+		spec.TCPSocket = &TCPSocketAction{Port: port}
+
+		check := spec.GetCheck(ctn)
+		success, err := check.Run(context.Background())
+		require.False(t, success)
+		require.EqualError(t, err, "failed to get the address of the container: container has no network yet")
+	})
+	t.Run("no_address", func(t *testing.T) {
+		ctn := &addressedContainer{}
+		spec := NewProbeSpec()
+		spec.HTTPGet = &HTTPGetAction{Path: "/", Port: httpPort}
+
+		check := spec.GetCheck(ctn)
+		success, err := check.Run(context.Background())
+		require.False(t, success)
+		require.EqualError(t, err, "container has no address")
+	})
+}
@@ -1,36 +1,105 @@
 package controller
 
-// TODO: Add TCPSocket (https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.11/#probe-v1-core)
-
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"os"
 	"os/exec"
+	"regexp"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 // A Check is a simple interface that will be easily mocked for testing purposes. It mirrors almost
 // exactly what Kubernetes calls an "Action" in its API, and only has a "run" function associated
-// with it.
+// with it. Run must give up as soon as the context is done, the probes cancel it when they stop
+// waiting for the check.
 type Check interface {
-	Run() (success bool, err error)
+	Run(ctx context.Context) (success bool, err error)
 }
 
 var _ Check = HTTPCheck{}
+var _ Check = TCPCheck{}
+var _ Check = GRPCCheck{}
 var _ Check = ShellCheck{}
 var _ Check = HealthyCheck{}
+var _ Check = RunnerCheck{}
+var _ Check = CheckFunc(nil)
+var _ Check = &AsyncCheck{}
+
+// A ContextFreeCheck is a check that cannot be cancelled, like the checks were before they
+// took a context.
+type ContextFreeCheck interface {
+	Run() (success bool, err error)
+}
 
-// A RunnerCheck implements the Check interface and calls the Runner function.
+// WithContext adapts a ContextFreeCheck to the Check interface. The check keeps running in the
+// background when the context is done, but Run returns right away.
+func WithContext(check ContextFreeCheck) Check {
+	return CheckFunc(func(ctx context.Context) (bool, error) {
+		return runWithContext(ctx, check.Run)
+	})
+}
+
+// CheckFunc implements the Check interface with a plain function.
+type CheckFunc func(ctx context.Context) (bool, error)
+
+// Run implements Check.Run.
+func (fn CheckFunc) Run(ctx context.Context) (bool, error) { return fn(ctx) }
+
+// orFailingCheck returns the check, or a check that always fails with the error if there
+// is one.
+func orFailingCheck(check Check, err error) Check {
+	if err != nil {
+		return CheckFunc(func(context.Context) (bool, error) { return false, err })
+	}
+	return check
+}
+
+// A RunnerCheck implements the Check interface and calls the Runner function. Run returns as
+// soon as the context is done, whether the Runner honors it or not.
 type RunnerCheck struct {
-	Runner func() error
+	Runner func(ctx context.Context) error
 }
 
 // Run implements Check.Run.
-func (check RunnerCheck) Run() (bool, error) {
-	if err := check.Runner(); err != nil {
-		return false, err
+func (check RunnerCheck) Run(ctx context.Context) (bool, error) {
+	return runWithContext(ctx, func() (bool, error) {
+		if err := check.Runner(ctx); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+// runWithContext runs the check function in the background and returns its result, or
+// the error of the context if it is done first.
+func runWithContext(ctx context.Context, fn func() (bool, error)) (bool, error) {
+	done := make(chan tickResult, 1)
+	go func() {
+		success, err := fn()
+		done <- tickResult{success, err}
+	}()
+	select {
+	case res := <-done:
+		return res.success, res.err
+	case <-ctx.Done():
+		return false, errors.WithStack(ctx.Err())
 	}
-	return true, nil
 }
 
 type AsyncCheck struct {
@@ -39,22 +108,47 @@ type AsyncCheck struct {
 	Start func() error
 	Wait  func() error
 
+	// Kill is optional, it gets called when the check is stopped so that Wait
+	// returns early.
+	Kill func() error
+
 	waiting bool
+	stopped bool
 }
 
 func NewAsyncCheck(start, wait func() error) *AsyncCheck {
 	return &AsyncCheck{Start: start, Wait: wait}
 }
 
-func (check *AsyncCheck) Run() (bool, error) {
+// Run starts the check and waits for it, stopping it if the context gets done in the
+// meantime.
+func (check *AsyncCheck) Run(ctx context.Context) (bool, error) {
 	if err := check.Start(); err != nil {
 		return false, err
 	}
 
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			check.Stop()
+		case <-finished:
+		}
+	}()
+
 	check.Lock()
 	check.waiting = true
+	stopped := check.stopped
 	check.Unlock()
 
+	// If the check got stopped while we were starting, we kill right away.
+	if stopped && check.Kill != nil {
+		if err := check.Kill(); err != nil {
+			return false, err
+		}
+	}
+
 	if err := check.Wait(); err != nil {
 		return false, err
 	}
@@ -67,6 +161,18 @@ func (check *AsyncCheck) Waiting() bool {
 	return check.waiting
 }
 
+// Stop calls Kill if the check is already waiting, or makes sure that Kill gets
+// called as soon as it starts waiting.
+func (check *AsyncCheck) Stop() error {
+	check.Lock()
+	defer check.Unlock()
+	check.stopped = true
+	if check.waiting && check.Kill != nil {
+		return check.Kill()
+	}
+	return nil
+}
+
 // A ShellCheck implements the Check interface and runs a command, reporting an error if the
 // exit code is not 0.
 type ShellCheck struct {
@@ -81,32 +187,75 @@ func NewShellCheck(cmd *exec.Cmd) ShellCheck {
 }
 
 // Run runs the command and returns an error if the command has an error, returning
-// true and no error otherwise.
-func (check ShellCheck) Run() (bool, error) {
-	if err := check.Cmd.Run(); err != nil {
+// true and no error otherwise. The command gets killed if the context is done before
+// it exits.
+func (check ShellCheck) Run(ctx context.Context) (bool, error) {
+	if err := check.Cmd.Start(); err != nil {
 		return false, err
 	}
-	return true, nil
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	case <-ctx.Done():
+		check.Cmd.Process.Kill()
+		<-done
+		return false, errors.WithStack(ctx.Err())
+	}
 }
 
 // HealthyCheck always returns a healthy bit set to true and no error.
 type HealthyCheck struct{}
 
 // Run implements Check.Run.
-func (HealthyCheck) Run() (bool, error) { return true, nil }
+func (HealthyCheck) Run(context.Context) (bool, error) { return true, nil }
 
-// An HTTPCheck sends a GET request to the host/path provided and checks the status code to
-// determine if the check succeeded or failed.
+// An HTTPCheck sends a request to the host/path provided and checks the status code to
+// determine if the check succeeded or failed. The method defaults to GET. The status code
+// must be one of SuccessCodes or fall in one of SuccessRanges, or be one of
+// DefaultSuccessRanges if neither is set. When ExpectedBody or ExpectedBodyRegexp is set,
+// the beginning of the body of the response must also contain the string or match the
+// regular expression.
 type HTTPCheck struct {
 	Scheme  string
+	Method  string
 	Host    string
 	Path    string
 	Headers []HTTPHeader
 
-	Client       HTTPDoer
-	SuccessCodes []int
+	Client        HTTPDoer
+	SuccessCodes  []int
+	SuccessRanges []StatusCodeRange
+
+	ExpectedBody       string
+	ExpectedBodyRegexp *regexp.Regexp
+}
+
+// StatusCodeRange is an inclusive range of HTTP status codes.
+type StatusCodeRange struct {
+	From int
+	To   int
+}
+
+func (r StatusCodeRange) contains(code int) bool {
+	return r.From <= code && code <= r.To
 }
 
+// DefaultSuccessRanges are the status codes that make an HTTP check succeed by default, the
+// same as in Kubernetes.
+var DefaultSuccessRanges = []StatusCodeRange{{From: 200, To: 399}}
+
+// maxBodyLength is how much of the body of the response gets matched against the expected
+// body.
+const maxBodyLength = 10 * 1024
+
 type HTTPHeader struct {
 	Name  string
 	Value string
@@ -120,12 +269,31 @@ var ErrBadStatusCode = fmt.Errorf("Bad Status Code")
 
 func NewHTTPCheck(host string, path string) HTTPCheck {
 	return HTTPCheck{
-		Scheme:       "http",
-		Host:         host,
-		Path:         path,
-		Client:       http.DefaultClient,
-		SuccessCodes: []int{http.StatusOK},
+		Scheme: "http",
+		Method: http.MethodGet,
+		Host:   host,
+		Path:   path,
+		Client: NewHTTPClient(0, nil, true),
+	}
+}
+
+// NewHTTPClient returns the client of a single HTTP check. It does not keep connections
+// alive between the runs of the check and gives up on requests after the timeout, if it
+// is not 0.
+func NewHTTPClient(timeout time.Duration, tlsConfig *tls.Config, followRedirects bool) *http.Client {
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+	}
+	if !followRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
 	}
+	return client
 }
 
 func (check *HTTPCheck) AddHeader(name, value string) {
@@ -135,30 +303,176 @@ func (check *HTTPCheck) AddHeader(name, value string) {
 	})
 }
 
-func (check HTTPCheck) Run() (bool, error) {
+// Run sends the request, which gets cancelled if the context is done before the response
+// comes back.
+func (check HTTPCheck) Run(ctx context.Context) (bool, error) {
 	url := fmt.Sprintf("%s://%s%s", check.Scheme, check.Host, check.Path)
-	req, err := http.NewRequest("GET", url, nil)
+	method := check.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
 		return false, err
 	}
+	req = req.WithContext(ctx)
 	for _, header := range check.Headers {
+		if strings.EqualFold(header.Name, "Host") {
+			req.Host = header.Value
+			continue
+		}
 		req.Header.Add(header.Name, header.Value)
 	}
 
 	resp, err := check.Client.Do(req)
 	if err != nil {
 		return false, err
-	} else if !intsContain(check.SuccessCodes, resp.StatusCode) {
+	}
+	body := []byte{}
+	if resp.Body != nil {
+		defer resp.Body.Close()
+		if check.ExpectedBody != "" || check.ExpectedBodyRegexp != nil {
+			body, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxBodyLength))
+			if err != nil {
+				return false, errors.Wrapf(err, "failed to read the response of %s", url)
+			}
+		}
+	}
+
+	if !check.successCode(resp.StatusCode) {
 		return false, ErrBadStatusCode
+	} else if check.ExpectedBody != "" && !bytes.Contains(body, []byte(check.ExpectedBody)) {
+		return false, fmt.Errorf("response of %s does not contain %q", url, check.ExpectedBody)
+	} else if check.ExpectedBodyRegexp != nil && !check.ExpectedBodyRegexp.Match(body) {
+		return false, fmt.Errorf("response of %s does not match %q", url, check.ExpectedBodyRegexp)
+	}
+	return true, nil
+}
+
+func (check HTTPCheck) successCode(code int) bool {
+	ranges := check.SuccessRanges
+	if len(check.SuccessCodes) == 0 && len(ranges) == 0 {
+		ranges = DefaultSuccessRanges
+	}
+	for _, r := range ranges {
+		if r.contains(code) {
+			return true
+		}
+	}
+	return intsContain(check.SuccessCodes, code)
+}
+
+// A TCPCheck succeeds if it can open a TCP connection to the address, the connection is
+// closed right away. It gives up connecting after Timeout, if it is set. The connection is
+// opened with Dial when it is set, from the host otherwise.
+type TCPCheck struct {
+	Address string
+	Timeout time.Duration
+	Dial    func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+func NewTCPCheck(address string, timeout time.Duration) TCPCheck {
+	return TCPCheck{
+		Address: address,
+		Timeout: timeout,
+	}
+}
+
+// Run implements Check.Run. Its error tells a connection that got refused apart from one
+// that timed out.
+func (check TCPCheck) Run(ctx context.Context) (bool, error) {
+	if check.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, check.Timeout)
+		defer cancel()
+	}
+	dial := check.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dial(ctx, "tcp", check.Address)
+	if err != nil {
+		if connectionRefused(err) {
+			return false, fmt.Errorf("connection refused by %s", check.Address)
+		} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return false, fmt.Errorf("timed out connecting to %s", check.Address)
+		}
+		return false, errors.Wrapf(err, "failed to connect to %s", check.Address)
+	}
+	conn.Close()
+	return true, nil
+}
+
+// connectionRefused returns true if the error comes from a connection that got refused.
+func connectionRefused(err error) bool {
+	opErr, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	syscallErr, ok := opErr.Err.(*os.SyscallError)
+	return ok && syscallErr.Err == syscall.ECONNREFUSED
+}
+
+// A GRPCCheck calls the standard grpc.health.v1.Health/Check RPC on the address, and succeeds
+// if the service is SERVING. An empty service asks for the health of the whole server. With
+// InsecureTLS the connection goes over TLS but the certificate of the server is not verified,
+// like for the HTTPS probes of Kubernetes. The connection is opened with Dial if it is set.
+type GRPCCheck struct {
+	Address     string
+	Service     string
+	InsecureTLS bool
+	Timeout     time.Duration
+	Dial        func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+func NewGRPCCheck(address, service string, timeout time.Duration) GRPCCheck {
+	return GRPCCheck{
+		Address: address,
+		Service: service,
+		Timeout: timeout,
+	}
+}
+
+// Run implements Check.Run, it opens a new connection for every run.
+func (check GRPCCheck) Run(ctx context.Context) (bool, error) {
+	if check.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, check.Timeout)
+		defer cancel()
+	}
+
+	creds := insecure.NewCredentials()
+	if check.InsecureTLS {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if check.Dial != nil {
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return check.Dial(ctx, "tcp", address)
+		}))
+	}
+	conn, err := grpc.DialContext(ctx, check.Address, opts...)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to connect to %s", check.Address)
+	}
+	defer conn.Close()
+
+	req := &healthpb.HealthCheckRequest{Service: check.Service}
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, req)
+	if err != nil {
+		return false, errors.Wrapf(err, "health check of %s failed", check.Address)
+	} else if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return false, fmt.Errorf("service %q of %s is %v", check.Service, check.Address, resp.Status)
 	}
 	return true, nil
 }
 
 // ExitCheck takes a Container returned by the ContainerBootstrapper and returns a Check
-// that syncronously Starts and Waits.
+// that syncronously Starts and Waits. Stopping the check kills the container.
 func ExitCheck(ctn Container) *AsyncCheck {
 	return &AsyncCheck{
 		Start: ctn.Start,
 		Wait:  ctn.Wait,
+		Kill:  func() error { return ctn.Kill(int(syscall.SIGKILL)) },
 	}
 }
@@ -1,30 +1,52 @@
 package controller
 
 import (
+	"context"
 	"errors"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os/exec"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/apourchet/fakenet"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestShellCheck(t *testing.T) {
 	t.Run("healthy_run", func(t *testing.T) {
 		cmd := exec.Command("sleep", "0.1")
 		check := NewShellCheck(cmd)
-		success, err := check.Run()
+		success, err := check.Run(context.Background())
 		require.True(t, success)
 		require.NoError(t, err)
 	})
 	t.Run("unhealthy_run", func(t *testing.T) {
 		cmd := exec.Command("unknowncommand", "unknownarg")
 		check := NewShellCheck(cmd)
-		success, err := check.Run()
+		success, err := check.Run(context.Background())
 		require.False(t, success)
 		require.Error(t, err)
 	})
+	t.Run("cancelled_run", func(t *testing.T) {
+		cmd := exec.Command("sleep", "10")
+		check := NewShellCheck(cmd)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		success, err := check.Run(ctx)
+		require.False(t, success)
+		require.Error(t, err)
+		require.True(t, time.Since(start) < 5*time.Second)
+		require.NotNil(t, cmd.ProcessState, "the command should have been killed")
+	})
 }
 
 func TestHTTPCheck(t *testing.T) {
@@ -37,7 +59,7 @@ func TestHTTPCheck(t *testing.T) {
 		check.Client = net
 		check.AddHeader("X-HEADER-NAME", "value")
 
-		success, err := check.Run()
+		success, err := check.Run(context.Background())
 		require.True(t, success)
 		require.NoError(t, err)
 	})
@@ -48,10 +70,36 @@ func TestHTTPCheck(t *testing.T) {
 		check := NewHTTPCheck("bogus", "/")
 		check.Client = net
 
-		success, err := check.Run()
+		success, err := check.Run(context.Background())
 		require.False(t, success)
 		require.Equal(t, ErrBadStatusCode, err)
 	})
+	t.Run("success_codes", func(t *testing.T) {
+		net := fakenet.New()
+		net.CatchAll(http.StatusBadRequest, "Bad Request")
+		net.InterceptURL("http://bogus/teapot", http.StatusTeapot, "I'm a teapot")
+		net.InterceptURL("http://bogus/accepted", http.StatusAccepted, "Accepted")
+		net.InterceptURL("http://bogus/unavailable", http.StatusServiceUnavailable, "Unavailable")
+
+		// The success codes replace the default ranges, the ranges supplement them.
+		check := NewHTTPCheck("bogus", "/teapot")
+		check.Client = net
+		check.SuccessCodes = []int{http.StatusTeapot}
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+
+		check.Path = "/accepted"
+		success, err = check.Run(context.Background())
+		require.False(t, success)
+		require.Equal(t, ErrBadStatusCode, err)
+
+		check.Path = "/unavailable"
+		check.SuccessRanges = []StatusCodeRange{{From: 500, To: 599}}
+		success, err = check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
 	t.Run("bad_url", func(t *testing.T) {
 		net := fakenet.New()
 		net.CatchAll(http.StatusOK, "OK")
@@ -60,7 +108,7 @@ func TestHTTPCheck(t *testing.T) {
 		check.Client = net
 		check.Scheme = "bad_scheme!"
 
-		success, err := check.Run()
+		success, err := check.Run(context.Background())
 		require.False(t, success)
 		require.Error(t, err)
 	})
@@ -72,8 +120,251 @@ func TestHTTPCheck(t *testing.T) {
 		check := NewHTTPCheck("bogus", "/")
 		check.Client = net
 
-		success, err := check.Run()
+		success, err := check.Run(context.Background())
+		require.False(t, success)
+		require.Error(t, err)
+	})
+}
+
+func TestHTTPCheckCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	check := NewHTTPCheck(server.Listener.Addr().String(), "/")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	success, err := check.Run(ctx)
+	require.False(t, success)
+	require.Error(t, err)
+	require.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestRunnerCheck(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		check := RunnerCheck{Runner: func(context.Context) error { return nil }}
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		check := RunnerCheck{Runner: func(context.Context) error {
+			<-block
+			return nil
+		}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		success, err := check.Run(ctx)
 		require.False(t, success)
 		require.Error(t, err)
 	})
 }
+
+type contextFreeCheck struct{ block chan struct{} }
+
+func (check contextFreeCheck) Run() (bool, error) {
+	<-check.block
+	return true, nil
+}
+
+func TestWithContext(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		block := make(chan struct{})
+		close(block)
+		success, err := WithContext(contextFreeCheck{block}).Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		success, err := WithContext(contextFreeCheck{block}).Run(ctx)
+		require.False(t, success)
+		require.Error(t, err)
+	})
+}
+
+// execContainer is a container whose execs block until they get killed.
+type execContainer struct {
+	mockContainer
+	killed chan struct{}
+}
+
+func (ctn *execContainer) ExecContext(ctx context.Context, program string, arguments ...string) (int, error) {
+	<-ctx.Done()
+	close(ctn.killed)
+	return 137, errors.New("killed")
+}
+
+func TestExecCheckCancel(t *testing.T) {
+	ctn := &execContainer{killed: make(chan struct{})}
+	check := NewProbeSpec().setExec("sleep", "10").GetCheck(ctn)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	success, err := check.Run(ctx)
+	require.False(t, success)
+	require.Error(t, err)
+	select {
+	case <-ctn.killed:
+	case <-time.After(time.Second):
+		t.Fatal("exec was not killed when the check got cancelled")
+	}
+}
+
+func TestTCPCheck(t *testing.T) {
+	t.Run("connected", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		check := NewTCPCheck(listener.Addr().String(), time.Second)
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("connection_refused", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		address := listener.Addr().String()
+		listener.Close()
+
+		check := NewTCPCheck(address, time.Second)
+		success, err := check.Run(context.Background())
+		require.False(t, success)
+		require.EqualError(t, err, "connection refused by "+address)
+	})
+	t.Run("timeout", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+
+		check := NewTCPCheck(listener.Addr().String(), time.Second)
+		success, err := check.Run(ctx)
+		require.False(t, success)
+		require.EqualError(t, err, "timed out connecting to "+listener.Addr().String())
+	})
+	t.Run("from_spec", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		port := listener.Addr().(*net.TCPAddr).Port
+
+		spec := NewProbeSpec()
+		spec.TCPSocket = &TCPSocketAction{Host: "127.0.0.1", Port: port}
+		check := spec.GetCheck(&mockContainer{})
+		require.Equal(t, NewTCPCheck(listener.Addr().String(), time.Second), check)
+
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+}
+
+// startHealthServer serves the gRPC health checking protocol on a local port, it returns
+// the health server along with the address it listens on.
+func startHealthServer(t *testing.T, opts ...grpc.ServerOption) (*health.Server, string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(opts...)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	return healthServer, listener.Addr().String(), server.Stop
+}
+
+func TestGRPCCheck(t *testing.T) {
+	t.Run("serving", func(t *testing.T) {
+		_, address, stop := startHealthServer(t)
+		defer stop()
+
+		check := NewGRPCCheck(address, "", time.Second)
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("not_serving", func(t *testing.T) {
+		server, address, stop := startHealthServer(t)
+		defer stop()
+		server.SetServingStatus("backend", healthpb.HealthCheckResponse_NOT_SERVING)
+
+		check := NewGRPCCheck(address, "backend", time.Second)
+		success, err := check.Run(context.Background())
+		require.False(t, success)
+		require.EqualError(t, err, `service "backend" of `+address+" is NOT_SERVING")
+
+		server.SetServingStatus("backend", healthpb.HealthCheckResponse_SERVING)
+		success, err = check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("unknown_service", func(t *testing.T) {
+		_, address, stop := startHealthServer(t)
+		defer stop()
+
+		check := NewGRPCCheck(address, "unknown", time.Second)
+		success, err := check.Run(context.Background())
+		require.False(t, success)
+		require.Error(t, err)
+	})
+	t.Run("no_server", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		address := listener.Addr().String()
+		listener.Close()
+
+		check := NewGRPCCheck(address, "", time.Second)
+		success, err := check.Run(context.Background())
+		require.False(t, success)
+		require.Error(t, err)
+	})
+	t.Run("insecure_tls", func(t *testing.T) {
+		// The server borrows the self-signed certificate of an HTTPS test server.
+		https := httptest.NewTLSServer(http.NotFoundHandler())
+		cert := https.TLS.Certificates[0]
+		https.Close()
+		_, address, stop := startHealthServer(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+		defer stop()
+
+		check := NewGRPCCheck(address, "", time.Second)
+		success, err := check.Run(context.Background())
+		require.False(t, success)
+		require.Error(t, err)
+
+		check.InsecureTLS = true
+		success, err = check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("from_spec", func(t *testing.T) {
+		_, address, stop := startHealthServer(t)
+		defer stop()
+		_, portStr, err := net.SplitHostPort(address)
+		require.NoError(t, err)
+		port, err := strconv.Atoi(portStr)
+		require.NoError(t, err)
+
+		spec := NewProbeSpec()
+		spec.GRPC = &GRPCAction{Port: port}
+		check := spec.GetCheck(&mockContainer{})
+		success, err := check.Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+}
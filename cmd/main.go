@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -19,6 +20,7 @@ type Application struct {
 	RuntimePath string
 	SpecPath    string
 	StatusPort  int
+	KillTimeout time.Duration
 }
 
 var app Application
@@ -27,6 +29,7 @@ func init() {
 	flag.StringVar(&app.RuntimePath, "runtime", "./bins/shellout.so", "The path to the runtime plugin library")
 	flag.StringVar(&app.SpecPath, "spec", "/spec.json", "The path to the podspec to start")
 	flag.IntVar(&app.StatusPort, "port", 8888, "The port that we will listen on to report the status of the pod")
+	flag.DurationVar(&app.KillTimeout, "kill-timeout", 60*time.Second, "The maximum time to wait for the pod to terminate on /kill")
 }
 
 func main() {
@@ -46,9 +49,14 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to initialize pod controller: %v", err)
 	}
-	ctrl.Start()
+	if err := ctrl.Start(); err != nil {
+		log.Fatalf("failed to start pod controller: %v", err)
+	}
 	log.Println("pod controller started")
 
+	if spec.Mode == controller.PodModeJob {
+		go exitOnCompletion(ctrl)
+	}
 	createHandlers(ctrl)
 	if err = http.ListenAndServe(fmt.Sprintf(":%d", app.StatusPort), nil); err != nil {
 		log.Fatalf("failed to create listen on given port %d: %v", app.StatusPort, err)
@@ -68,14 +76,54 @@ func createHandlers(ctrl controller.PodController) {
 		w.WriteHeader(http.StatusOK)
 		w.Write(content)
 	})
+	http.HandleFunc("/initstatus", func(w http.ResponseWriter, r *http.Request) {
+		statuses := ctrl.InitStatus()
+		content, err := json.Marshal(statuses)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(content)
+	})
+	http.HandleFunc("/phase", func(w http.ResponseWriter, r *http.Request) {
+		content := fmt.Sprintf(`{"phase":%q,"reason":%q,"restarts":%d}`, ctrl.Phase(), ctrl.Reason(), ctrl.Restarts())
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(content))
+	})
 	http.HandleFunc("/healthy", func(w http.ResponseWriter, r *http.Request) {
-		healthy := ctrl.Healthy()
-		content := fmt.Sprintf(`{"healthy":%v}`, healthy)
+		report := ctrl.Health()
+		content, err := json.Marshal(map[string]interface{}{
+			"healthy":    report.Healthy(),
+			"health":     report.Health.String(),
+			"reason":     report.Reason,
+			"containers": report.Containers,
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
 		w.WriteHeader(http.StatusOK)
+		w.Write(content)
+	})
+	http.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		ready := ctrl.Ready()
+		content := fmt.Sprintf(`{"ready":%v}`, ready)
+		if ready {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
 		w.Write([]byte(content))
 	})
 	http.HandleFunc("/kill", func(w http.ResponseWriter, r *http.Request) {
-		ctrl.Kill(9)
+		ctx, cancel := context.WithTimeout(context.Background(), app.KillTimeout)
+		defer cancel()
+		if err := ctrl.Terminate(ctx); err != nil {
+			log.Printf("failed to terminate pod: %v", err)
+		}
 		w.WriteHeader(http.StatusOK)
 		go func() {
 			time.Sleep(100 * time.Millisecond)
@@ -84,6 +132,16 @@ func createHandlers(ctrl controller.PodController) {
 	})
 }
 
+// exitOnCompletion waits for the job to complete and exits with the exit code of the pod.
+func exitOnCompletion(ctrl controller.PodController) {
+	result, err := ctrl.Wait(context.Background())
+	if err != nil {
+		log.Fatalf("failed to wait for the pod to complete: %v", err)
+	}
+	log.Printf("pod completed: %v: %s\n", result.Phase, result.Reason)
+	os.Exit(result.ExitCode)
+}
+
 func unmarshal(contents []byte, spec *controller.PodSpec) error {
 	jsonErr := json.Unmarshal(contents, spec)
 	if jsonErr == nil {
@@ -8,12 +8,14 @@ import (
 type ContainerState int
 
 const (
-	Started  ContainerState = iota
-	Healthy                 // When the liveness probe is healthy
-	Failing                 // When the liveness probe last failed after a healthy state
-	Terminal                // When the liveness probe has given up
-	Finished                // When the container exited with a 0 status code
-	Failed                  // When the container exited with non-0 status code
+	Started          ContainerState = iota
+	Healthy                         // When the liveness probe is healthy
+	Failing                         // When the liveness probe last failed after a healthy state
+	Terminal                        // When the container was killed after failing its probes and was not restarted
+	Finished                        // When the container exited with a 0 status code
+	Failed                          // When the container exited with non-0 status code
+	Waiting                         // When the container is waiting on its dependencies to be launched
+	CrashLoopBackOff                // When the container keeps exiting and is backing off before its next restart
 )
 
 func (state ContainerState) String() string {
@@ -30,6 +32,10 @@ func (state ContainerState) String() string {
 		return "FINISHED"
 	case Failed:
 		return "FAILED"
+	case Waiting:
+		return "WAITING"
+	case CrashLoopBackOff:
+		return "CRASHLOOPBACKOFF"
 	}
 	return "UNKNOWN"
 }
@@ -41,6 +47,45 @@ type ContainerStatus struct {
 	States       []ContainerState
 	LatestErrors []*ProbeError
 	Restarts     int
+
+	// WaitingReason explains what the container is waiting on while it is in the
+	// Waiting state.
+	WaitingReason string
+
+	// StartedAt is the last time the container was launched. RestartBackoff is
+	// the delay that was applied before the last restart, or that is being applied
+	// before the next one if NextRestart is set.
+	StartedAt      time.Time
+	RestartBackoff time.Duration
+	NextRestart    time.Time
+	RestartReason  string
+
+	// RestartTimes are the times of the restarts of the container that happened within
+	// its crash loop window, and RemainingBackoff is how long is left before the next
+	// restart.
+	RestartTimes     []time.Time
+	RemainingBackoff time.Duration
+
+	// Termination is set once the controller has terminated the container.
+	Termination *Termination
+
+	// ProgressStalled is set while the container has been Started for longer than the
+	// progress deadline of the pod, in which case it is not healthy.
+	ProgressStalled bool
+
+	// ExitCode is the exit code of the last run of the container, once it exited.
+	ExitCode int
+
+	// Ready tells whether the container is able to serve according to its readiness
+	// probe.
+	Ready ReadyCondition
+}
+
+// ReadyCondition is true while the container is running and its readiness probe is
+// healthy. LastTransitionTime is the last time the condition changed.
+type ReadyCondition struct {
+	Status             bool
+	LastTransitionTime time.Time
 }
 
 type ProbeError struct {
@@ -79,24 +124,230 @@ func (status *ContainerStatus) AddError(err *ProbeError) {
 	status.LatestErrors = append(status.LatestErrors, err)
 }
 
+// RecordError adds the error to the latest errors of the container. An error that was
+// already recorded is moved to the end of the list with its new timestamp instead, so
+// that the errors the probes keep reporting do not grow the list.
+func (status *ContainerStatus) RecordError(err *ProbeError) {
+	status.Lock()
+	defer status.Unlock()
+	for i, latest := range status.LatestErrors {
+		if latest.Message == err.Message {
+			status.LatestErrors = append(status.LatestErrors[:i], status.LatestErrors[i+1:]...)
+			break
+		}
+	}
+	status.LatestErrors = append(status.LatestErrors, err)
+}
+
 func (status *ContainerStatus) AddState(state ContainerState) {
 	status.Lock()
 	defer status.Unlock()
 	status.States = append(status.States, state)
 }
 
-func (status *ContainerStatus) RecordRestart() {
+// RecordRestart increments the restart count of the container and clears the
+// restart that was scheduled for it.
+func (status *ContainerStatus) RecordRestart(now time.Time) {
 	status.Lock()
 	defer status.Unlock()
 	status.Restarts++
+	status.RestartTimes = append(status.RestartTimes, now)
+	status.NextRestart = time.Time{}
+	status.RemainingBackoff = 0
 }
 
-// Healthy returns true if the container is in one of the 3 states:
-// Started, Healthy, Failing
+// RecordPodRestart increments the restart count of the container when it gets restarted
+// along with the rest of the pod, the container then waits to be launched again.
+func (status *ContainerStatus) RecordPodRestart(now time.Time, reason string) {
+	status.Lock()
+	defer status.Unlock()
+	status.Restarts++
+	status.RestartTimes = append(status.RestartTimes, now)
+	status.NextRestart = time.Time{}
+	status.RemainingBackoff = 0
+	status.RestartReason = reason
+	status.WaitingReason = "waiting for the pod to restart"
+	status.States = append(status.States, Waiting)
+}
+
+// RestartsSince prunes the restarts that happened before the given time, and returns
+// how many are left.
+func (status *ContainerStatus) RestartsSince(since time.Time) int {
+	status.Lock()
+	defer status.Unlock()
+	recent := []time.Time{}
+	for _, at := range status.RestartTimes {
+		if !at.Before(since) {
+			recent = append(recent, at)
+		}
+	}
+	status.RestartTimes = recent
+	return len(recent)
+}
+
+// UpdateRemainingBackoff refreshes how long is left before the scheduled restart of the
+// container.
+func (status *ContainerStatus) UpdateRemainingBackoff(now time.Time) {
+	status.Lock()
+	defer status.Unlock()
+	status.RemainingBackoff = 0
+	if !status.NextRestart.IsZero() && now.Before(status.NextRestart) {
+		status.RemainingBackoff = status.NextRestart.Sub(now)
+	}
+}
+
+// RecordStart marks the time at which the container got launched, moving it out
+// of the Waiting state if it was in it.
+func (status *ContainerStatus) RecordStart(now time.Time) {
+	status.Lock()
+	defer status.Unlock()
+	status.StartedAt = now
+	status.WaitingReason = ""
+	if status.States[len(status.States)-1] == Waiting {
+		status.States = append(status.States, Started)
+	}
+}
+
+// RecordWaiting moves the container to the Waiting state with the given reason.
+func (status *ContainerStatus) RecordWaiting(reason string) {
+	status.Lock()
+	defer status.Unlock()
+	status.WaitingReason = reason
+	if status.States[len(status.States)-1] != Waiting {
+		status.States = append(status.States, Waiting)
+	}
+}
+
+// Uptime returns how long the container has been up since its last launch.
+func (status *ContainerStatus) Uptime(now time.Time) time.Duration {
+	status.Lock()
+	defer status.Unlock()
+	return now.Sub(status.StartedAt)
+}
+
+// ScheduleRestart records that the container will be relaunched after the
+// backoff delay, and why.
+func (status *ContainerStatus) ScheduleRestart(backoff time.Duration, at time.Time, reason string) {
+	status.Lock()
+	defer status.Unlock()
+	status.RestartBackoff = backoff
+	status.NextRestart = at
+	status.RestartReason = reason
+}
+
+// DelayRestart pushes back the restart scheduled for the container by the backoff delay,
+// keeping the reason for it. It is used when relaunching the container failed.
+func (status *ContainerStatus) DelayRestart(backoff time.Duration, at time.Time) {
+	status.Lock()
+	defer status.Unlock()
+	status.RestartBackoff = backoff
+	status.NextRestart = at
+}
+
+// LastBackoff returns the delay that was applied before the last restart.
+func (status *ContainerStatus) LastBackoff() time.Duration {
+	status.Lock()
+	defer status.Unlock()
+	return status.RestartBackoff
+}
+
+// CancelRestart clears the restart that was scheduled for the container, if any.
+func (status *ContainerStatus) CancelRestart() {
+	status.Lock()
+	defer status.Unlock()
+	status.NextRestart = time.Time{}
+	status.RemainingBackoff = 0
+}
+
+// SetProgressStalled records whether the container went past the progress deadline
+// of the pod.
+func (status *ContainerStatus) SetProgressStalled(stalled bool) {
+	status.Lock()
+	defer status.Unlock()
+	status.ProgressStalled = stalled
+}
+
+// RecordExit records the exit code of the container.
+func (status *ContainerStatus) RecordExit(code int) {
+	status.Lock()
+	defer status.Unlock()
+	status.ExitCode = code
+}
+
+// LastExitCode returns the exit code of the last run of the container.
+func (status *ContainerStatus) LastExitCode() int {
+	status.Lock()
+	defer status.Unlock()
+	return status.ExitCode
+}
+
+// SetReady updates the ready condition of the container, the transition time is only
+// changed if the condition did.
+func (status *ContainerStatus) SetReady(ready bool, now time.Time) {
+	status.Lock()
+	defer status.Unlock()
+	if status.Ready.Status != ready || status.Ready.LastTransitionTime.IsZero() {
+		status.Ready = ReadyCondition{Status: ready, LastTransitionTime: now}
+	}
+}
+
+// IsReady returns true if the container has been ready for at least minReady.
+func (status *ContainerStatus) IsReady(now time.Time, minReady time.Duration) bool {
+	status.Lock()
+	defer status.Unlock()
+	return status.Ready.Status && now.Sub(status.Ready.LastTransitionTime) >= minReady
+}
+
+func (status *ContainerStatus) RecordTermination(termination *Termination) {
+	status.Lock()
+	defer status.Unlock()
+	status.Termination = termination
+}
+
+// RecordShutdownTier records the tier in which the container was terminated, and how
+// long it took for the whole tier to exit.
+func (status *ContainerStatus) RecordShutdownTier(tier int, duration time.Duration) {
+	status.Lock()
+	defer status.Unlock()
+	if status.Termination != nil {
+		status.Termination.Tier = tier
+		status.Termination.TierDuration = duration
+	}
+}
+
+// LastTermination returns how the container was terminated, or nil if it was not.
+func (status *ContainerStatus) LastTermination() *Termination {
+	status.Lock()
+	defer status.Unlock()
+	return status.Termination
+}
+
+// RestartPending returns true if a restart was scheduled for the container.
+func (status *ContainerStatus) RestartPending() bool {
+	status.Lock()
+	defer status.Unlock()
+	return !status.NextRestart.IsZero()
+}
+
+// RestartDue returns true if a restart was scheduled and its backoff has elapsed.
+func (status *ContainerStatus) RestartDue(now time.Time) bool {
+	status.Lock()
+	defer status.Unlock()
+	return !status.NextRestart.IsZero() && !now.Before(status.NextRestart)
+}
+
+// Healthy returns true if the container is in one of the 4 states:
+// Waiting, Started, Healthy, Failing
 // A status of Failing means that the liveness probe has failed but has not reached the
 // failureThreshold. So in essence the container is still in a valid state, but most likely
 // transitioning into a failed state soon if the liveness probe keeps failing.
+// A container that stayed Started past the progress deadline of the pod is not healthy.
 func (status *ContainerStatus) Healthy() bool {
-	lastState := status.LastState()
-	return lastState == Started || lastState == Healthy || lastState == Failing
+	status.Lock()
+	defer status.Unlock()
+	lastState := status.States[len(status.States)-1]
+	if status.ProgressStalled {
+		return false
+	}
+	return lastState == Waiting || lastState == Started || lastState == Healthy || lastState == Failing
 }
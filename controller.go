@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -17,24 +20,92 @@ import (
 type PodController interface {
 	Start() error
 	Status() []*ContainerStatus
+	InitStatus() []*InitContainerStatus
+
+	// Phase sums up where the pod is in its lifecycle, from the init sequence
+	// to the completion of its containers.
+	Phase() PodPhase
+
+	// Restarts counts the restarts of the whole pod, the restarts of each container
+	// are in its status.
+	Restarts() int
+
+	// Reason explains why the pod failed when it was for a pod-wide cause, such as
+	// DeadlineExceeded.
+	Reason() string
 
 	// Kill tries to send the signal to the containers and returns the status
 	// of the containers.
 	Kill(signal int) []error
 
+	// Terminate gracefully stops the containers, sending each of them its stop signal
+	// and escalating to SIGKILL once their grace period runs out.
+	Terminate(ctx context.Context) error
+
 	// This is the healthy bit that the pod controller should aim to get right
 	// as it will determine when the pod should get rescheduled.
 	Healthy() bool
+
+	// Health is the richer version of the healthy bit, telling apart healthy and
+	// degraded pods and listing the containers that contribute to the verdict.
+	Health() HealthReport
+
+	// Ready tells whether the pod is able to serve, which is when all of its main
+	// containers have been ready for at least MinReadySeconds.
+	Ready() bool
+
+	// Wait blocks until the pod completes, see PodMode, and returns its result.
+	Wait(ctx context.Context) (PodResult, error)
+
+	// Shutdown stops the background work of the controller and kills the containers,
+	// then waits for all of its goroutines to return or for the context to expire.
+	Shutdown(ctx context.Context) error
 }
 
 type PodSpec struct {
 	InitContainers []InitContainerSpec
 	Containers     []ContainerSpec
+
+	// Mode is either service, the default, or job.
+	Mode PodMode
+
+	// RestartPolicy is either Container, the default, or Pod. The Backoff settings
+	// apply in between restarts of the whole pod.
+	RestartPolicy PodRestartPolicy
+	Backoff       BackoffSpec
+
+	// ShutdownOrder lists the tiers of containers in the order in which they get
+	// terminated. It defaults to the reverse of the dependency order.
+	ShutdownOrder [][]string
+
+	// HealthPolicy decides how the health of the containers adds up to the health
+	// of the pod, all of them need to be healthy by default.
+	HealthPolicy HealthPolicySpec
+
+	// ActiveDeadlineSeconds bounds how long the pod can run for, after which all of
+	// its containers get terminated and the pod fails with DeadlineExceeded.
+	ActiveDeadlineSeconds int
+
+	// ProgressDeadlineSeconds is how long a container can stay Started without becoming
+	// healthy before it counts as unhealthy.
+	ProgressDeadlineSeconds int
+
+	// MinReadySeconds is how long a container must have been ready for before it
+	// counts towards the readiness of the pod.
+	MinReadySeconds int
 }
 
 type InitContainerSpec struct {
-	Name     string
-	Spec     oci.Spec
+	Name string
+	Spec oci.Spec
+
+	// ActiveDeadlineSeconds bounds the time a single run of the init container can
+	// take before it gets killed. A failed run is retried up to Retries times, waiting
+	// in between runs according to the Backoff settings.
+	ActiveDeadlineSeconds int
+	Retries               int
+	Backoff               BackoffSpec
+
 	Metadata map[string]interface{}
 }
 
@@ -44,10 +115,39 @@ type ContainerSpec struct {
 	LivenessProbe  LivenessProbeSpec
 	ReadinessProbe ReadinessProbeSpec
 
+	// StartupProbe is optional, the liveness and readiness probes only start once
+	// it has succeeded.
+	StartupProbe *StartupProbeSpec
+
+	// RestartPolicy decides whether the container gets relaunched after it exits,
+	// waiting in between restarts according to the Backoff settings.
+	RestartPolicy RestartPolicy
+	Backoff       BackoffSpec
+
+	// StopSignal is sent to the container when the pod gets terminated, defaulting
+	// to SIGTERM. The container is killed if it has not exited after its grace period,
+	// which defaults to 30 seconds.
+	StopSignal                    int
+	TerminationGracePeriodSeconds *int
+
+	// Role is either main, the default, or sidecar.
+	Role ContainerRole
+
+	// Optional containers are left out of the health policy of the pod, they can only
+	// make it degraded.
+	Optional bool
+
+	// DependsOn lists the containers that must meet a condition before this one
+	// gets launched.
+	DependsOn []Dependency
+
+	Lifecycle Lifecycle
+
 	Metadata map[string]interface{}
 }
 
 type ContainerInfo struct {
+	spec   ContainerSpec
 	ctn    Container
 	probes *ProbeSet
 	status *ContainerStatus
@@ -55,14 +155,55 @@ type ContainerInfo struct {
 
 // controller implements the PodController interface.
 type controller struct {
+	sync.Mutex
+
 	// A map from container ID/name to container status
-	InitInfos map[string]ContainerInfo
+	InitInfos map[string]InitContainerInfo
 	MainInfos map[string]ContainerInfo
 
 	InitOrder []string
 	MainOrder []string
 
 	Clock clock.Clock
+
+	// HealthPolicy aggregates the statuses of the containers into the healthy bit of
+	// the pod.
+	HealthPolicy HealthPolicy
+
+	// The bootstrapper is kept around so that containers can be materialized again,
+	// since the ones it returns cannot be started more than once.
+	bootstrapper ContainerBootstrapper
+
+	stop        chan struct{}
+	stopOnce    sync.Once
+	wg          sync.WaitGroup
+	terminating bool
+	interrupted chan struct{} // Closed once Terminate gets called
+
+	launched           map[string]bool
+	terminated         map[string]chan struct{} // Closed once the termination of the container is over
+	sidecarsTerminated bool
+	shutdownOrder      [][]string
+	minReady           time.Duration
+
+	started     bool
+	initialized bool
+	initErr     error
+
+	mode      PodMode
+	completed chan struct{}
+	result    PodResult
+
+	activeDeadline   time.Duration
+	progressDeadline time.Duration
+	exceeded         chan struct{}
+	reason           string
+
+	podRestartPolicy PodRestartPolicy
+	backoff          BackoffSpec
+	podRestarts      int
+	podBackoff       time.Duration
+	podStartedAt     time.Time
 }
 
 func NewPodController(spec PodSpec, runtimePath string) (*controller, error) {
@@ -78,7 +219,12 @@ func WithBootstrapper(spec PodSpec, bootstrapper ContainerBootstrapper) (*contro
 	if err != nil {
 		return nil, err
 	}
-	return WithContainers(spec, initContainers, mainContainers)
+	c, err := WithContainers(spec, initContainers, mainContainers)
+	if err != nil {
+		return c, err
+	}
+	c.bootstrapper = bootstrapper
+	return c, nil
 }
 
 func WithContainers(spec PodSpec, initContainers, mainContainers []Container) (*controller, error) {
@@ -86,14 +232,35 @@ func WithContainers(spec PodSpec, initContainers, mainContainers []Container) (*
 		return nil, fmt.Errorf("Missing names for some of the containers")
 	}
 	c := &controller{
-		InitInfos: map[string]ContainerInfo{},
-		MainInfos: map[string]ContainerInfo{},
-		Clock:     clock.New(),
+		InitInfos:     map[string]InitContainerInfo{},
+		MainInfos:     map[string]ContainerInfo{},
+		Clock:         clock.New(),
+		stop:          make(chan struct{}),
+		interrupted:   make(chan struct{}),
+		launched:      map[string]bool{},
+		terminated:    map[string]chan struct{}{},
+		shutdownOrder: spec.ShutdownOrder,
+		minReady:      time.Duration(spec.MinReadySeconds) * time.Second,
+		mode:          spec.Mode,
+		completed:     make(chan struct{}),
+
+		activeDeadline:   time.Duration(spec.ActiveDeadlineSeconds) * time.Second,
+		progressDeadline: time.Duration(spec.ProgressDeadlineSeconds) * time.Second,
+		exceeded:         make(chan struct{}),
+
+		podRestartPolicy: spec.RestartPolicy,
+		backoff:          spec.Backoff,
+	}
+	if err := spec.Mode.validate(); err != nil {
+		return c, err
+	} else if err := spec.RestartPolicy.validate(); err != nil {
+		return c, err
 	}
 	for i, ctn := range initContainers {
 		ctnSpec := spec.InitContainers[i]
-		status := NewContainerStatus(ctnSpec.Name)
-		c.InitInfos[ctnSpec.Name] = ContainerInfo{
+		status := NewInitContainerStatus(ctnSpec.Name)
+		c.InitInfos[ctnSpec.Name] = InitContainerInfo{
+			spec:   ctnSpec,
 			ctn:    ctn,
 			status: status,
 		}
@@ -101,39 +268,91 @@ func WithContainers(spec PodSpec, initContainers, mainContainers []Container) (*
 	}
 	for i, ctn := range mainContainers {
 		ctnSpec := spec.Containers[i]
+		if err := ctnSpec.RestartPolicy.validate(); err != nil {
+			return c, errors.Wrapf(err, "container %s", ctnSpec.Name)
+		} else if err := ctnSpec.Role.validate(); err != nil {
+			return c, errors.Wrapf(err, "container %s", ctnSpec.Name)
+		} else if err := ctnSpec.Lifecycle.validate(ctn); err != nil {
+			return c, errors.Wrapf(err, "container %s", ctnSpec.Name)
+		}
 		status := NewContainerStatus(ctnSpec.Name)
 		probeSet, err := c.getProbeSet(ctnSpec, ctn)
 		if err != nil {
-			return c, err
+			return c, errors.Wrapf(err, "container %s", ctnSpec.Name)
 		}
 		c.MainInfos[ctnSpec.Name] = ContainerInfo{
+			spec:   ctnSpec,
 			ctn:    ctn,
 			status: status,
 			probes: probeSet,
 		}
 		c.MainOrder = append(c.MainOrder, ctnSpec.Name)
 	}
+	var err error
+	if len(c.MainOrder) > 0 && len(c.mainContainers()) == 0 {
+		return c, fmt.Errorf("pod has no main container, only sidecars")
+	} else if c.HealthPolicy, err = spec.HealthPolicy.Materialize(c.requiredContainers()); err != nil {
+		return c, err
+	} else if err := c.validateDependencies(); err != nil {
+		return c, err
+	} else if err := c.validateShutdownOrder(); err != nil {
+		return c, err
+	}
 	return c, nil
 }
 
-// Start goes through the spec of the controller and starts the init containers
-// as well as regular containers. It also starts a background thread that watches
-// the probes to update the container statuses.
+// Start returns immediately after starting a background thread that goes through the
+// spec of the controller and runs the init containers, then starts the regular containers
+// and watches their probes to update the container statuses. The progress of the pod can
+// be followed through Phase.
 func (c *controller) Start() error {
-	// First run the InitContainers of the pod. These containers will be
-	// run in sequence, and not healthchecked.
+	c.Lock()
+	defer c.Unlock()
+	if c.started {
+		return fmt.Errorf("pod controller was already started")
+	}
+	c.started = true
+	c.podStartedAt = c.Clock.Now()
+	c.enforceDeadline()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if c.initialize() {
+			c.watch()
+		}
+		if err := c.initError(); err != nil && c.Reason() == "" {
+			c.complete(PodResult{Phase: PodFailed, Reason: err.Error(), ExitCode: 1})
+		}
+	}()
+	return nil
+}
+
+// initialize runs the InitContainers of the pod. These containers will be run in sequence,
+// and not healthchecked. It returns false if the regular containers must not be started.
+func (c *controller) initialize() bool {
 	for _, name := range c.InitOrder {
-		info := c.InitInfos[name]
-		// Start that init container, then wait for it to run to completion.
-		if err := info.ctn.Start(); err != nil {
-			return errors.WithStack(err)
-		} else if err := info.ctn.Wait(); err != nil {
-			return errors.WithStack(err)
+		if c.isTerminating() {
+			c.abandon()
+			return false
+		}
+		if err := c.runInit(name); err != nil {
+			c.Lock()
+			c.initErr = err
+			c.Unlock()
+			return false
 		}
-		// TODO: timeout these init containers.
 	}
-	go c.watch()
-	return nil
+
+	c.Lock()
+	c.initialized = true
+	terminating := c.terminating
+	c.Unlock()
+	if terminating {
+		c.abandon()
+		return false
+	}
+	return true
 }
 
 // Status gathers the statuses of all the containers and appends them to a list
@@ -141,7 +360,18 @@ func (c *controller) Start() error {
 func (c *controller) Status() []*ContainerStatus {
 	statuses := []*ContainerStatus{}
 	for _, name := range c.MainOrder {
-		info := c.MainInfos[name]
+		info := c.getInfo(name)
+		statuses = append(statuses, info.status)
+	}
+	return statuses
+}
+
+// InitStatus gathers the statuses of the init containers in the order in which
+// they run.
+func (c *controller) InitStatus() []*InitContainerStatus {
+	statuses := []*InitContainerStatus{}
+	for _, name := range c.InitOrder {
+		info := c.getInitInfo(name)
 		statuses = append(statuses, info.status)
 	}
 	return statuses
@@ -151,7 +381,7 @@ func (c *controller) Status() []*ContainerStatus {
 func (c *controller) Kill(signal int) []error {
 	errs := []error{}
 	for _, name := range c.MainOrder {
-		info := c.MainInfos[name]
+		info := c.getInfo(name)
 		if err := info.ctn.Kill(signal); err != nil {
 			err = fmt.Errorf("failed to send kill signal %d to container %s: %v",
 				signal, name, err)
@@ -165,15 +395,86 @@ func (c *controller) Kill(signal int) []error {
 	return errs
 }
 
+// Shutdown stops the watch loop, then stops the probes of every container, which kills
+// the containers that are still running. It then waits for all of the background goroutines
+// to return. If the context expires before that, the error lists what was still running.
+// The containers get killed even if the watch loop does not return in time.
+func (c *controller) Shutdown(ctx context.Context) error {
+	c.stopOnce.Do(func() { close(c.stop) })
+
+	watching := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(watching)
+	}()
+	interrupted := []string{}
+	select {
+	case <-watching:
+	case <-ctx.Done():
+		interrupted = append(interrupted, "watch loop still running")
+
+		// The watch loop may still relaunch a container before it returns, the probes
+		// get stopped once more when it does so that nothing is left running.
+		go func() {
+			<-watching
+			c.stopProbes()
+		}()
+	}
+
+	pending := c.stopProbes()
+	running := []string{}
+	for _, name := range c.MainOrder {
+		select {
+		case <-pending[name]:
+			continue
+		default:
+		}
+		select {
+		case <-pending[name]:
+		case <-ctx.Done():
+			running = append(running, name)
+		}
+	}
+	if len(running) > 0 {
+		interrupted = append(interrupted, "containers still running: "+strings.Join(running, ", "))
+	}
+	if len(interrupted) > 0 {
+		return fmt.Errorf("shutdown interrupted: %s: %v", strings.Join(interrupted, "; "), ctx.Err())
+	}
+	return nil
+}
+
+// stopProbes stops the probes of every container, and returns the channels closed once
+// the probes of each of them have returned.
+func (c *controller) stopProbes() map[string]<-chan struct{} {
+	pending := map[string]<-chan struct{}{}
+	for _, name := range c.MainOrder {
+		info := c.getInfo(name)
+		info.probes.Stop()
+		pending[name] = info.probes.Done()
+	}
+	return pending
+}
+
 // Healthy only looks through the container statuses to determine the health of the pod,
 // it relies on the eventual consistency provided by the background thread that the controller
 // spawns with `watch`.
-// For now if a single container within the pod is neither Started nor Healthy we deem the pod
-// to be unhealthy and should be rescheduled.
+// The statuses are aggregated by the health policy of the pod, by default if a single container
+// within the pod is not healthy we deem the pod to be unhealthy and should be rescheduled.
 func (c *controller) Healthy() bool {
-	for _, name := range c.MainOrder {
-		info := c.MainInfos[name]
-		if !info.status.Healthy() {
+	return c.Health().Healthy()
+}
+
+// Ready returns true if all of the main containers have been ready for at least the
+// minimum ready time of the pod. Like Healthy, it relies on the statuses kept up to date
+// by `watch`.
+func (c *controller) Ready() bool {
+	if c.initFailed() {
+		return false
+	}
+	now := c.Clock.Now()
+	for _, name := range c.mainContainers() {
+		if !c.getInfo(name).status.IsReady(now, c.minReady) {
 			return false
 		}
 	}
@@ -184,96 +485,346 @@ func (c *controller) Healthy() bool {
 // goes through all of the probes for all the containers and updates the statuses of
 // the containers within the pod. It does this pass every second.
 func (c *controller) watch() {
-	for _, info := range c.MainInfos {
-		info.probes.Start()
-	}
 	for {
-		c.Clock.Sleep(1 * time.Second)
-		for _, info := range c.MainInfos {
-			status, probeset := info.status, info.probes
-			lastState := status.LastState()
-
-			// If we get an error we havent seen before we will append it to our list
-			// of latest errors.
-			state, mustRestart, errs := c.nextState(lastState, probeset)
-			errs = filterErrors(errs)
-			if len(errs) > 0 {
-				if status.LatestError().Message != errs[0].Error() {
-					for _, msg := range stringifyErrors(errs) {
-						err := &ProbeError{Message: msg, Timestamp: c.Clock.Now()}
-						status.AddError(err)
-					}
-				} else {
-					status.LatestError().Timestamp = c.Clock.Now()
-				}
+		c.launchPending()
+		select {
+		case <-c.stop:
+			return
+		case <-c.Clock.After(1 * time.Second):
+		}
+		for _, name := range c.MainOrder {
+			if c.isLaunched(name) {
+				c.update(name)
 			}
-
-			// If the state does not change, just continue to the next set
-			// of probes.
-			if state == status.LastState() {
-				continue
+		}
+		if reason := c.podRestartReason(); reason != "" {
+			if !c.restartPod(reason) {
+				return
 			}
-			status.AddState(state)
-
-			if mustRestart {
-				status.RecordRestart()
-				// TODO: restart container and change newStatus
+			continue
+		}
+		c.terminateSidecars()
+
+		if result, done := c.completion(); done && c.Reason() == "" {
+			// A job only completes once the rest of the pod is terminated, so that
+			// nothing is left running when Wait returns.
+			if c.mode == PodModeJob {
+				c.finishJob()
+				c.complete(result)
+				return
 			}
+			c.complete(result)
+		}
+	}
+}
+
+// update runs a single pass over the probes of the container and updates its status,
+// relaunching the container if a restart was scheduled and its backoff has elapsed. A
+// relaunch that fails is tried again after the next backoff delay.
+func (c *controller) update(name string) {
+	info := c.getInfo(name)
+	status, probeset := info.status, info.probes
+	if !c.isTerminated(name) && status.RestartDue(c.Clock.Now()) {
+		if err := c.restart(name); err != nil {
+			now := c.Clock.Now()
+			status.AddError(&ProbeError{
+				Message:   fmt.Sprintf("failed to restart container %s: %v", name, err),
+				Timestamp: now,
+			})
+			backoff := info.spec.Backoff.Next(status.LastBackoff(), 0)
+			status.DelayRestart(backoff, now.Add(backoff))
+			status.UpdateRemainingBackoff(now)
+		}
+		return
+	}
+	status.UpdateRemainingBackoff(c.Clock.Now())
+	status.SetProgressStalled(c.progressStalled(status, c.Clock.Now()))
+	lastState := status.LastState()
+
+	// A container that failed its liveness or startup probe gets killed, it is then
+	// restarted according to its policy like any container that exited.
+	reason, graceful := c.probeFailure(info)
+	if reason != "" && probeset.Exit.Running() && probeset.MarkKilled() {
+		c.kill(info, reason, graceful)
+	}
+
+	// When the failure of the container restarts the whole pod, the container itself is
+	// never restarted on its own.
+	policy := info.spec.RestartPolicy
+	if c.restartsPod(info.spec) {
+		policy = RestartNever
+	}
 
-			// TODO: Prune that new status so that memory never explodes.
+	// If we get an error we havent seen before we will append it to our list
+	// of latest errors, the ones we have already seen only get their timestamp
+	// refreshed.
+	state, mustRestart, errs := c.nextState(lastState, policy, probeset)
+	for _, msg := range stringifyErrors(errs) {
+		status.RecordError(&ProbeError{Message: msg, Timestamp: c.Clock.Now()})
+	}
+
+	// A container is only ready while it is running and its readiness probe passes.
+	ready, _ := probeset.Readiness.Healthy()
+	switch state {
+	case Started, Healthy, Failing:
+	default:
+		ready = false
+	}
+	status.SetReady(ready && probeset.Readiness.Running(), c.Clock.Now())
+
+	// If the state does not change, just continue to the next set
+	// of probes.
+	if state == lastState {
+		return
+	}
+
+	// The restart gets scheduled before the state is recorded so that the container
+	// is never seen as exited for good. A container that keeps exiting shortly after
+	// its restarts is backing off in a crash loop.
+	if state == Finished || state == Failed || state == Terminal {
+		_, err := probeset.Exit.Healthy()
+		status.RecordExit(exitCode(err))
+	}
+	if mustRestart && !c.isTerminated(name) {
+		probeset.Stop()
+		now := c.Clock.Now()
+		backoff := info.spec.Backoff.Next(status.LastBackoff(), status.Uptime(now))
+		if reason == "" {
+			_, err := probeset.Exit.Healthy()
+			reason = fmt.Sprintf("container exited with code %d", exitCode(err))
+		}
+		status.ScheduleRestart(backoff, now.Add(backoff), reason)
+		status.UpdateRemainingBackoff(now)
+		if info.spec.Backoff.CrashLooping(status.RestartsSince(now.Add(-info.spec.Backoff.CrashLoopWindow()))) {
+			state = CrashLoopBackOff
 		}
-		// TODO stopping mechanism
 	}
+	status.AddState(state)
+
+	// TODO: Prune that new status so that memory never explodes.
+}
+
+// launch starts the probes of the container, which in turn start the container itself.
+func (c *controller) launch(name string) {
+	c.Lock()
+	c.launched[name] = true
+	c.Unlock()
+
+	info := c.getInfo(name)
+	info.status.RecordStart(c.Clock.Now())
+	info.probes.Start()
+}
+
+// restart materializes the container again and launches it. The status of the container
+// is kept so that the restart count and errors carry over. The container is not launched
+// if it got terminated in the meantime, which is checked under the lock that Terminate
+// takes so that the new container is never left running behind its back.
+func (c *controller) restart(name string) error {
+	info, err := c.rematerialize(name)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if _, terminated := c.terminated[name]; c.terminating || terminated {
+		return nil
+	}
+	c.launched[name] = true
+	info.status.RecordRestart(c.Clock.Now())
+	info.status.AddState(Started)
+	info.status.RecordStart(c.Clock.Now())
+	info.probes.Start()
+	return nil
 }
 
 // nextState computes the next state for the container from its status. It also computes
-// whether or not the container needs to be restarted and returns some of the errors the probes
-// might have run into.
-func (c *controller) nextState(state ContainerState, probes *ProbeSet) (next ContainerState, restart bool, errs []error) {
+// whether or not the container needs to be restarted according to its restart policy and
+// returns some of the errors the probes might have run into.
+func (c *controller) nextState(state ContainerState, policy RestartPolicy, probes *ProbeSet) (next ContainerState, restart bool, errs []error) {
 	exitHealth, exitErr := probes.Exit.Healthy()
 	exitRunning := probes.Exit.Running()
 
 	liveHealth, liveErr := probes.Liveness.Healthy()
-	liveStarted, liveRunning := probes.Liveness.Started(), probes.Liveness.Running()
+	liveStarted := probes.Liveness.Started()
+	probesFailed := probes.StartupFailed() || probes.LivenessFailed()
 
 	errs = []error{exitErr, liveErr}
+	if probes.Startup != nil {
+		_, startErr := probes.Startup.Healthy()
+		errs = append(errs, startErr)
+	}
 
-	// TODO: restart computation
-	restart = false
 	switch state {
-	case Failed, Finished, Terminal:
-		return state, restart, errs
+	case Waiting, Failed, Finished, Terminal, CrashLoopBackOff:
+		return state, false, errs
 	case Started, Healthy, Failing:
-		// If the container exited we can get the next state easily.
-		if !exitRunning {
+		// A failed postStart hook gets the container killed.
+		if err := probes.PostStartError(); err != nil {
+			return Failed, policy.ShouldRestart(Failed), append([]error{err}, errs...)
+		}
+
+		// If the container exited we can get the next state easily. A container that
+		// got killed because of its probes has failed, and is Terminal if it does not
+		// get restarted.
+		if !exitRunning && probesFailed {
+			if policy.ShouldRestart(Failed) {
+				return Failed, true, errs
+			}
+			return Terminal, false, errs
+		} else if !exitRunning {
+			next = Failed
 			if exitHealth {
-				return Finished, restart, errs
+				next = Finished
 			}
-			return Failed, restart, errs
+			return next, policy.ShouldRestart(next), errs
 		}
 
-		// If the liveness has not started yet then it means the exit probe is still
-		// in its starting phase.
-		if !liveStarted {
-			return Started, restart, errs
+		// If the liveness or startup probe gave up the container is being killed.
+		if probesFailed {
+			return Failing, false, errs
 		}
 
-		// If the container did not exit yet we need to check that the liveness
-		// probe has not given up.
-		if !liveRunning {
-			return Terminal, restart, errs
+		// If the liveness has not started yet then it means the exit probe is still
+		// in its starting phase, or that the startup probe has not succeeded yet.
+		if !liveStarted {
+			return Started, false, errs
 		}
 
 		// If the liveness probe is still running we just return healthy or not
 		// depending on its bit.
 		if liveHealth {
-			return Healthy, restart, errs
+			return Healthy, false, errs
 		}
-		return Failing, restart, errs
+		return Failing, false, errs
 	default:
 		panic(fmt.Sprintf("unrecognized state: %v", state))
 	}
-	return
+}
+
+// probeFailure returns why the container must be killed if it failed its startup or its
+// liveness probe, and whether it must be given its grace period.
+func (c *controller) probeFailure(info ContainerInfo) (reason string, graceful bool) {
+	if info.probes.StartupFailed() {
+		return "startup probe failed", info.spec.StartupProbe.GracefulKill
+	} else if info.probes.LivenessFailed() {
+		return "liveness probe failed", info.spec.LivenessProbe.GracefulKill
+	}
+	return "", false
+}
+
+// kill brings down the container in the background after it failed its probes. The
+// container is only sent its stop signal if it must be killed gracefully.
+func (c *controller) kill(info ContainerInfo, reason string, graceful bool) {
+	grace := time.Duration(0)
+	if graceful {
+		grace = info.spec.gracePeriod()
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ctx, cancel := c.stopContext()
+		defer cancel()
+
+		termination := &Termination{Signal: info.spec.stopSignal()}
+		if err := c.signal(ctx, info, grace, termination); err != nil {
+			err = errors.Wrapf(err, "failed to kill container %s after its %s", info.spec.Name, reason)
+			info.status.AddError(&ProbeError{Message: err.Error(), Timestamp: c.Clock.Now()})
+		}
+	}()
+}
+
+// rematerialize builds a new container for the given name through the bootstrapper,
+// along with a new exit probe and probe set, and swaps them into MainInfos. If the
+// controller was not given a bootstrapper the current container is reused.
+func (c *controller) rematerialize(name string) (ContainerInfo, error) {
+	info := c.getInfo(name)
+	if info.status == nil {
+		return info, fmt.Errorf("unknown container %s", name)
+	}
+
+	ctn := info.ctn
+	if c.bootstrapper != nil {
+		var err error
+		ctn, err = c.bootstrapper(info.spec.Spec, info.spec.Metadata)
+		if err != nil {
+			return info, errors.WithStack(err)
+		}
+	}
+	probes, err := c.getProbeSet(info.spec, ctn)
+	if err != nil {
+		return info, err
+	}
+
+	info.ctn, info.probes = ctn, probes
+	c.setInfo(name, info)
+	return info, nil
+}
+
+func (c *controller) initFailed() bool {
+	return c.initError() != nil
+}
+
+func (c *controller) initError() error {
+	c.Lock()
+	defer c.Unlock()
+	return c.initErr
+}
+
+func (c *controller) isTerminating() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.terminating
+}
+
+// isTerminated returns true if the container is being, or has been, terminated by
+// the controller, in which case it must not be restarted.
+func (c *controller) isTerminated(name string) bool {
+	c.Lock()
+	defer c.Unlock()
+	_, terminated := c.terminated[name]
+	return c.terminating || terminated
+}
+
+func (c *controller) isLaunched(name string) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.launched[name]
+}
+
+// mainContainers returns the names of the containers that are not sidecars.
+func (c *controller) mainContainers() []string {
+	names := []string{}
+	for _, name := range c.MainOrder {
+		if !c.getInfo(name).spec.isSidecar() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// requiredContainers returns the names of the containers that are not optional.
+func (c *controller) requiredContainers() []string {
+	names := []string{}
+	for _, name := range c.MainOrder {
+		if !c.getInfo(name).spec.Optional {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (c *controller) getInfo(name string) ContainerInfo {
+	c.Lock()
+	defer c.Unlock()
+	return c.MainInfos[name]
+}
+
+func (c *controller) setInfo(name string, info ContainerInfo) {
+	c.Lock()
+	defer c.Unlock()
+	c.MainInfos[name] = info
 }
 
 func materializeContainers(spec PodSpec, bootstrapper ContainerBootstrapper) ([]Container, []Container, error) {
@@ -309,6 +860,12 @@ func (c *controller) getProbeSet(spec ContainerSpec, ctn Container) (*ProbeSet,
 
 	exitProbe := NewExitProbe(ExitCheck(ctn))
 	pset := NewProbeSet(exitProbe, livenessProbe, readinessProbe)
-	pset.Sleeper = func(d time.Duration) { c.Clock.Sleep(d) }
+	if spec.StartupProbe != nil {
+		if pset.Startup, err = spec.StartupProbe.Materialize(ctn); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	pset.After = func(d time.Duration) <-chan time.Time { return c.Clock.After(d) }
+	pset.PostStart = c.postStartHook(spec, ctn)
 	return pset, nil
 }
@@ -1,6 +1,11 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -9,6 +14,101 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// mockContainer is a single-use container, it refuses to be started twice just like
+// an exec.Cmd would. If running is set, Wait blocks until the container gets killed.
+type mockContainer struct {
+	sync.Mutex
+
+	exitErr    error
+	started    bool
+	gate       chan struct{}
+	running    chan struct{}
+	ignoreKill bool
+	ignoreTerm bool
+	killed     bool
+	signals    []int
+	exec       func(program string, arguments ...string) (int, error)
+}
+
+func newBlockingContainer(ignoreKill bool) *mockContainer {
+	return &mockContainer{
+		running:    make(chan struct{}),
+		ignoreKill: ignoreKill,
+	}
+}
+
+func (ctn *mockContainer) Start() error {
+	if ctn.gate != nil {
+		<-ctn.gate
+	}
+	ctn.Lock()
+	defer ctn.Unlock()
+	if ctn.started {
+		return errors.New("container already started")
+	}
+	ctn.started = true
+	return nil
+}
+
+func (ctn *mockContainer) Wait() error {
+	if ctn.running != nil {
+		<-ctn.running
+	}
+	return ctn.exitErr
+}
+
+func (ctn *mockContainer) Kill(signal int) error {
+	ctn.Lock()
+	defer ctn.Unlock()
+	ctn.signals = append(ctn.signals, signal)
+	if ctn.running == nil || ctn.ignoreKill || ctn.killed {
+		return nil
+	} else if ctn.ignoreTerm && signal != int(syscall.SIGKILL) {
+		return nil
+	}
+	ctn.killed = true
+	close(ctn.running)
+	return nil
+}
+
+func (ctn *mockContainer) Started() bool {
+	ctn.Lock()
+	defer ctn.Unlock()
+	return ctn.started
+}
+
+func (ctn *mockContainer) Killed() bool {
+	ctn.Lock()
+	defer ctn.Unlock()
+	return ctn.killed
+}
+
+func (ctn *mockContainer) Signals() []int {
+	ctn.Lock()
+	defer ctn.Unlock()
+	return append([]int{}, ctn.signals...)
+}
+
+func (ctn *mockContainer) Exec(program string, arguments ...string) (int, error) {
+	if ctn.exec != nil {
+		return ctn.exec(program, arguments...)
+	}
+	return 0, nil
+}
+
+// exitingContainer exits on its own just as it gets signaled, so that the signal fails
+// like it does for a process that is already gone.
+type exitingContainer struct {
+	*mockContainer
+	exited <-chan struct{}
+}
+
+func (ctn *exitingContainer) Kill(signal int) error {
+	ctn.mockContainer.Kill(signal)
+	<-ctn.exited
+	return errors.New("process already finished")
+}
+
 func TestController(t *testing.T) {
 	t.Run("single_healthy", func(t *testing.T) {
 		spec := PodSpec{
@@ -36,6 +136,7 @@ func TestController(t *testing.T) {
 		}
 		controller, err := NewPodController(spec, "./bins/testing.so")
 		require.NoError(t, err)
+		require.Equal(t, PodPending, controller.Phase())
 
 		clock := clock.NewMock()
 		controller.Clock = clock
@@ -46,11 +147,54 @@ func TestController(t *testing.T) {
 
 		healthy := controller.Healthy()
 		require.True(t, healthy)
+		require.Equal(t, PodRunning, controller.Phase())
 
 		statuses := controller.Status()
 		require.Lenf(t, statuses, 1, "should only have 1 status")
 		require.Equal(t, Healthy, statuses[0].LastState())
 	})
+	t.Run("no_probes", func(t *testing.T) {
+		exec := []string{"true"}
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name: "bare",
+					Spec: oci.Spec{
+						Process: &oci.Process{
+							Args: []string{"sleep", "100000"},
+						},
+					},
+				},
+				{
+					Name: "defaults",
+					Spec: oci.Spec{
+						Process: &oci.Process{
+							Args: []string{"sleep", "100000"},
+						},
+					},
+					LivenessProbe:  LivenessProbeSpec{ProbeSpec{Action: Action{Exec: &exec}}},
+					ReadinessProbe: ReadinessProbeSpec{ProbeSpec{Action: Action{Exec: &exec}}},
+				},
+			},
+		}
+		controller, err := NewPodController(spec, "./bins/testing.so")
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 10, time.Second)
+
+		require.True(t, controller.Healthy())
+		require.True(t, controller.Ready())
+		require.Equal(t, PodRunning, controller.Phase())
+		for _, status := range controller.Status() {
+			require.Equal(t, Healthy, status.LastState(), status.Name)
+			require.Equal(t, 0, status.Restarts)
+		}
+	})
 	t.Run("single_unhealthy", func(t *testing.T) {
 		spec := PodSpec{
 			Containers: []ContainerSpec{
@@ -83,6 +227,1904 @@ func TestController(t *testing.T) {
 		require.Lenf(t, statuses, 1, "should only have 1 status")
 		require.Equal(t, Terminal, statuses[0].LastState())
 	})
+	t.Run("restart_on_failure", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name: "main",
+					Spec: oci.Spec{
+						Process: &oci.Process{
+							Args: []string{"false"},
+						},
+					},
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartOnFailure,
+					Backoff:        BackoffSpec{InitialDelaySeconds: 2},
+				},
+			},
+		}
+		controller, err := NewPodController(spec, "./bins/testing.so")
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 10, time.Second)
+
+		statuses := controller.Status()
+		require.Lenf(t, statuses, 1, "should only have 1 status")
+		require.Equal(t, 2, statuses[0].Restarts)
+		require.Equal(t, 8*time.Second, statuses[0].LastBackoff())
+		require.Equal(t, PodRunning, controller.Phase())
+	})
+	t.Run("crash_loop", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name: "main",
+					Spec: oci.Spec{
+						Process: &oci.Process{
+							Args: []string{"false"},
+						},
+					},
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartAlways,
+					Backoff:        BackoffSpec{InitialDelaySeconds: 1, CrashLoopThreshold: 2},
+				},
+				{
+					Name: "other",
+					Spec: oci.Spec{
+						Process: &oci.Process{
+							Args: []string{"sleep", "100000"},
+						},
+					},
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+			HealthPolicy: HealthPolicySpec{Type: HealthPolicyAny},
+		}
+		controller, err := NewPodController(spec, "./bins/testing.so")
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		status := controller.Status()[0]
+		for i := 0; i < 20 && status.LastState() != CrashLoopBackOff; i++ {
+			timeTravel(clock, 1, time.Second)
+		}
+		require.Equal(t, CrashLoopBackOff, status.LastState())
+		require.Equal(t, "CRASHLOOPBACKOFF", status.LastState().String())
+		require.Equal(t, 2, status.Restarts)
+		require.Equal(t, 4*time.Second, status.RemainingBackoff)
+		// The other container is enough to meet the health policy of the pod.
+		require.True(t, controller.Healthy())
+		require.Equal(t, PodDegraded, controller.Health().Health)
+		require.Equal(t, []string{"main"}, controller.Health().Containers)
+		require.Equal(t, PodRunning, controller.Phase())
+
+		timeTravel(clock, 5, time.Second)
+		require.Equal(t, 3, status.Restarts)
+	})
+	t.Run("liveness_restart", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec().setExec("live")},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartAlways,
+					Backoff:        BackoffSpec{InitialDelaySeconds: 100},
+				},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		ctn.exec = func(string, ...string) (int, error) { return 1, nil }
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 4, time.Second)
+		require.Equal(t, []int{int(syscall.SIGKILL)}, ctn.Signals())
+		status := controller.Status()[0]
+		require.Equal(t, Failed, status.LastState())
+		require.True(t, status.RestartPending())
+		require.Equal(t, "liveness probe failed", status.RestartReason)
+	})
+	t.Run("liveness_graceful_kill", func(t *testing.T) {
+		liveness := NewProbeSpec().setExec("live")
+		liveness.GracefulKill = true
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{liveness},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		ctn.exec = func(string, ...string) (int, error) { return 1, nil }
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 4, time.Second)
+		require.Equal(t, []int{int(syscall.SIGTERM)}, ctn.Signals())
+		status := controller.Status()[0]
+		require.Equal(t, Terminal, status.LastState())
+		require.False(t, status.RestartPending())
+		require.Equal(t, PodFailed, controller.Phase())
+	})
+	t.Run("liveness_terminal", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec().setExec("live")},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		ctn.exitErr = exitError(137)
+		ctn.exec = func(string, ...string) (int, error) { return 1, nil }
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 5, time.Second)
+		status := controller.Status()[0]
+		require.Equal(t, Terminal, status.LastState())
+		require.Equal(t, 137, status.LastExitCode())
+		status.Lock()
+		errs := len(status.LatestErrors)
+		status.Unlock()
+		require.NotZero(t, errs)
+
+		// The errors of the killed container keep being reported on every pass, they
+		// are only recorded once.
+		timeTravel(clock, 20, time.Second)
+		status.Lock()
+		require.Len(t, status.LatestErrors, errs)
+		status.Unlock()
+
+		result, err := controller.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, PodResult{
+			Phase:    PodFailed,
+			Reason:   "container main failed its probes and was not restarted",
+			ExitCode: 137,
+		}, result)
+	})
+	t.Run("never_restart", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name: "main",
+					Spec: oci.Spec{
+						Process: &oci.Process{
+							Args: []string{"false"},
+						},
+					},
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartNever,
+				},
+			},
+		}
+		controller, err := NewPodController(spec, "./bins/testing.so")
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 10, time.Second)
+
+		statuses := controller.Status()
+		require.Lenf(t, statuses, 1, "should only have 1 status")
+		require.Equal(t, Failed, statuses[0].LastState())
+		require.Equal(t, 0, statuses[0].Restarts)
+		require.Equal(t, PodFailed, controller.Phase())
+	})
+	t.Run("restart_rematerializes", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartAlways,
+					Backoff:        BackoffSpec{InitialDelaySeconds: 1, MaxDelaySeconds: 1},
+				},
+			},
+		}
+		var lock sync.Mutex
+		bootstrapped := 0
+		bootstrapper := func(oci.Spec, map[string]interface{}) (Container, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			bootstrapped++
+			return &mockContainer{exitErr: errors.New("exit status 1")}, nil
+		}
+		controller, err := WithBootstrapper(spec, bootstrapper)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 10, time.Second)
+
+		statuses := controller.Status()
+		require.Lenf(t, statuses, 1, "should only have 1 status")
+		require.True(t, statuses[0].Restarts > 0)
+		require.Equal(t, "exit status 1", statuses[0].LatestError().Message)
+
+		lock.Lock()
+		defer lock.Unlock()
+		require.Equal(t, statuses[0].Restarts+1, bootstrapped)
+	})
+	t.Run("restart_failure_backoff", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartAlways,
+					Backoff:        BackoffSpec{InitialDelaySeconds: 1, MaxDelaySeconds: 4},
+				},
+			},
+		}
+		var lock sync.Mutex
+		bootstrapped := 0
+		bootstrapper := func(oci.Spec, map[string]interface{}) (Container, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			bootstrapped++
+			if bootstrapped > 1 {
+				return nil, errors.New("no space left on device")
+			}
+			return &mockContainer{exitErr: errors.New("exit status 1")}, nil
+		}
+		controller, err := WithBootstrapper(spec, bootstrapper)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 10, time.Second)
+
+		// The failed restarts back off instead of being tried again on every pass.
+		status := controller.Status()[0]
+		require.Equal(t, 0, status.Restarts)
+		require.True(t, status.RestartPending())
+		require.Equal(t, 4*time.Second, status.LastBackoff())
+		status.Lock()
+		failures := 0
+		for _, err := range status.LatestErrors {
+			if strings.Contains(err.Message, "no space left on device") {
+				failures++
+			}
+		}
+		status.Unlock()
+
+		lock.Lock()
+		defer lock.Unlock()
+		require.Equal(t, 4, bootstrapped)
+		require.Equal(t, 3, failures)
+	})
+	t.Run("restart_pod", func(t *testing.T) {
+		spec := PodSpec{
+			InitContainers: []InitContainerSpec{
+				{Name: "migrate", Spec: oci.Spec{Process: &oci.Process{Args: []string{"migrate"}}}},
+			},
+			Containers: []ContainerSpec{
+				{
+					Name:           "app",
+					Spec:           oci.Spec{Process: &oci.Process{Args: []string{"app"}}},
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "worker",
+					Spec:           oci.Spec{Process: &oci.Process{Args: []string{"worker"}}},
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+			RestartPolicy: PodRestartPod,
+			Backoff:       BackoffSpec{InitialDelaySeconds: 2},
+		}
+		var lock sync.Mutex
+		bootstrapped := map[string][]*mockContainer{}
+		bootstrapper := func(spec oci.Spec, _ map[string]interface{}) (Container, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			name := spec.Process.Args[0]
+			ctn := newBlockingContainer(false)
+			if name == "migrate" {
+				ctn = &mockContainer{}
+			}
+			bootstrapped[name] = append(bootstrapped[name], ctn)
+			return ctn, nil
+		}
+		containers := func(name string) []*mockContainer {
+			lock.Lock()
+			defer lock.Unlock()
+			return bootstrapped[name]
+		}
+		controller, err := WithBootstrapper(spec, bootstrapper)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.Equal(t, PodRunning, controller.Phase())
+		worker := containers("worker")[0]
+		worker.exitErr = errors.New("exit status 1")
+		worker.Kill(int(syscall.SIGKILL))
+
+		timeTravel(clock, 2, time.Second)
+		require.Equal(t, PodInitializing, controller.Phase())
+		require.Equal(t, []int{int(syscall.SIGTERM)}, containers("app")[0].Signals())
+
+		timeTravel(clock, 4, time.Second)
+		require.Equal(t, PodRunning, controller.Phase())
+		require.Equal(t, 1, controller.Restarts())
+		require.Len(t, containers("migrate"), 2)
+		require.Len(t, containers("app"), 2)
+		require.Len(t, containers("worker"), 2)
+		require.True(t, containers("app")[1].Started())
+
+		statuses := controller.Status()
+		for _, status := range statuses {
+			require.Equal(t, 1, status.Restarts)
+			require.Equal(t, "pod restarted: container worker is FAILED", status.RestartReason)
+			require.NotEqual(t, Waiting, status.LastState())
+		}
+		require.Equal(t, InitSucceeded, controller.InitStatus()[0].LastState())
+	})
+	t.Run("terminate_during_pod_restart", func(t *testing.T) {
+		spec := PodSpec{
+			Mode: PodModeJob,
+			InitContainers: []InitContainerSpec{
+				{Name: "migrate", Spec: oci.Spec{Process: &oci.Process{Args: []string{"migrate"}}}},
+			},
+			Containers: []ContainerSpec{
+				{
+					Name:           "worker",
+					Spec:           oci.Spec{Process: &oci.Process{Args: []string{"worker"}}},
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+			RestartPolicy: PodRestartPod,
+			Backoff:       BackoffSpec{InitialDelaySeconds: 10},
+		}
+		var lock sync.Mutex
+		workers := []*mockContainer{}
+		bootstrapper := func(spec oci.Spec, _ map[string]interface{}) (Container, error) {
+			if spec.Process.Args[0] == "migrate" {
+				return &mockContainer{}, nil
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			ctn := &mockContainer{exitErr: errors.New("exit status 1")}
+			workers = append(workers, ctn)
+			return ctn, nil
+		}
+		controller, err := WithBootstrapper(spec, bootstrapper)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		// The worker fails right away and the pod waits for its backoff to restart.
+		timeTravel(clock, 3, time.Second)
+		require.Equal(t, PodInitializing, controller.Phase())
+		err = controller.Terminate(context.Background())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		result, err := controller.Wait(ctx)
+		require.NoError(t, err)
+		require.Equal(t, PodResult{Phase: PodFailed, Reason: ReasonTerminated, ExitCode: 1}, result)
+		require.Equal(t, PodFailed, controller.Phase())
+		require.Equal(t, 0, controller.Restarts())
+		lock.Lock()
+		defer lock.Unlock()
+		require.Len(t, workers, 1)
+	})
+	t.Run("unknown_pod_restart_policy", func(t *testing.T) {
+		spec := PodSpec{
+			Containers:    []ContainerSpec{{Name: "main"}},
+			RestartPolicy: "Always",
+		}
+		_, err := WithContainers(spec, nil, []Container{&mockContainer{}})
+		require.Error(t, err)
+	})
+	t.Run("shutdown", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "sidecar",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		containers := []*mockContainer{newBlockingContainer(false), newBlockingContainer(false)}
+		controller, err := WithContainers(spec, nil, []Container{containers[0], containers[1]})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = controller.Shutdown(ctx)
+		require.NoError(t, err)
+		require.Equal(t, PodUnknown, controller.Phase())
+		require.True(t, containers[0].Killed())
+		require.True(t, containers[1].Killed())
+	})
+	t.Run("shutdown_timeout", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		controller, err := WithContainers(spec, nil, []Container{newBlockingContainer(true)})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err = controller.Shutdown(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "main")
+	})
+	t.Run("shutdown_watch_timeout", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					Spec:           oci.Spec{Process: &oci.Process{Args: []string{"main"}}},
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "worker",
+					Spec:           oci.Spec{Process: &oci.Process{Args: []string{"worker"}}},
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartAlways,
+					Backoff:        BackoffSpec{InitialDelaySeconds: 1},
+				},
+			},
+		}
+
+		// The restart of the worker blocks the watch loop until the gate gets opened.
+		var lock sync.Mutex
+		bootstrapped := map[string][]*mockContainer{}
+		blocked, gate := make(chan struct{}), make(chan struct{})
+		bootstrapper := func(spec oci.Spec, _ map[string]interface{}) (Container, error) {
+			name := spec.Process.Args[0]
+			lock.Lock()
+			restarting := name == "worker" && len(bootstrapped[name]) > 0
+			ctn := newBlockingContainer(false)
+			if name == "worker" && !restarting {
+				ctn = &mockContainer{exitErr: errors.New("exit status 1")}
+			}
+			bootstrapped[name] = append(bootstrapped[name], ctn)
+			lock.Unlock()
+			if restarting {
+				close(blocked)
+				<-gate
+			}
+			return ctn, nil
+		}
+		containers := func(name string) []*mockContainer {
+			lock.Lock()
+			defer lock.Unlock()
+			return bootstrapped[name]
+		}
+		controller, err := WithBootstrapper(spec, bootstrapper)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		go timeTravel(clock, 5, time.Second)
+		select {
+		case <-blocked:
+		case <-time.After(time.Second):
+			t.Fatal("worker was not restarted")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err = controller.Shutdown(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "watch loop still running")
+		require.True(t, containers("main")[0].Killed())
+
+		// The worker relaunched once the watch loop is unblocked gets killed as well.
+		close(gate)
+		select {
+		case <-containers("worker")[1].running:
+		case <-time.After(time.Second):
+			t.Fatal("relaunched worker was not killed")
+		}
+	})
+	t.Run("terminate_graceful", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartAlways,
+					Backoff:        BackoffSpec{InitialDelaySeconds: 1},
+				},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+
+		err = controller.Terminate(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []int{int(syscall.SIGTERM)}, ctn.Signals())
+
+		timeTravel(clock, 5, time.Second)
+
+		statuses := controller.Status()
+		require.Equal(t, Finished, statuses[0].LastState())
+		require.Equal(t, 0, statuses[0].Restarts)
+		termination := statuses[0].LastTermination()
+		require.NotNil(t, termination)
+		require.Equal(t, int(syscall.SIGTERM), termination.Signal)
+		require.False(t, termination.Killed)
+		require.True(t, termination.Exited)
+	})
+	t.Run("terminate_exited", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		ctn := &exitingContainer{mockContainer: newBlockingContainer(false)}
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+
+		// The container exits right as it gets its stop signal, which then fails.
+		ctn.exited = controller.getInfo("main").probes.Exit.Done()
+		err = controller.Terminate(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []int{int(syscall.SIGTERM)}, ctn.Signals())
+		termination := controller.Status()[0].LastTermination()
+		require.NotNil(t, termination)
+		require.Empty(t, termination.Error)
+		require.True(t, termination.Exited)
+	})
+	t.Run("terminate_escalates", func(t *testing.T) {
+		grace := 2
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:                          "main",
+					LivenessProbe:                 LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe:                ReadinessProbeSpec{NewProbeSpec()},
+					StopSignal:                    int(syscall.SIGINT),
+					TerminationGracePeriodSeconds: &grace,
+				},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		ctn.ignoreTerm = true
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+
+		terminated := make(chan error)
+		go func() { terminated <- controller.Terminate(context.Background()) }()
+		gosched()
+		timeTravel(clock, 3, time.Second)
+
+		require.NoError(t, <-terminated)
+		require.Equal(t, []int{int(syscall.SIGINT), int(syscall.SIGKILL)}, ctn.Signals())
+		termination := controller.Status()[0].LastTermination()
+		require.NotNil(t, termination)
+		require.True(t, termination.Killed)
+		require.True(t, termination.Exited)
+	})
+	t.Run("terminate_during_restart", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartAlways,
+					Backoff:        BackoffSpec{InitialDelaySeconds: 1},
+				},
+			},
+		}
+
+		// The restart of the container blocks until the gate gets opened.
+		var lock sync.Mutex
+		bootstrapped := []*mockContainer{}
+		blocked, gate := make(chan struct{}), make(chan struct{})
+		bootstrapper := func(oci.Spec, map[string]interface{}) (Container, error) {
+			lock.Lock()
+			ctn := newBlockingContainer(false)
+			if len(bootstrapped) == 0 {
+				ctn = &mockContainer{exitErr: errors.New("exit status 1")}
+			}
+			bootstrapped = append(bootstrapped, ctn)
+			restarting := len(bootstrapped) > 1
+			lock.Unlock()
+			if restarting {
+				close(blocked)
+				<-gate
+			}
+			return ctn, nil
+		}
+		controller, err := WithBootstrapper(spec, bootstrapper)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		travelled := make(chan struct{})
+		go func() {
+			timeTravel(clock, 5, time.Second)
+			close(travelled)
+		}()
+		select {
+		case <-blocked:
+		case <-time.After(time.Second):
+			t.Fatal("container was not restarted")
+		}
+
+		err = controller.Terminate(context.Background())
+		require.NoError(t, err)
+		close(gate)
+		<-travelled
+		timeTravel(clock, 3, time.Second)
+
+		lock.Lock()
+		defer lock.Unlock()
+		require.Len(t, bootstrapped, 2)
+		require.False(t, bootstrapped[1].Started())
+		require.Equal(t, 0, controller.Status()[0].Restarts)
+	})
+	t.Run("terminate_during_init", func(t *testing.T) {
+		spec := PodSpec{
+			InitContainers: []InitContainerSpec{
+				{Name: "init"},
+			},
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		initCtn, main := newBlockingContainer(false), newBlockingContainer(false)
+		controller, err := WithContainers(spec, []Container{initCtn}, []Container{main})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 2, time.Second)
+		require.Equal(t, InitRunning, controller.InitStatus()[0].LastState())
+		err = controller.Terminate(context.Background())
+		require.NoError(t, err)
+
+		result, err := controller.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, PodFailed, result.Phase)
+		require.True(t, initCtn.Killed())
+		require.False(t, main.Started())
+		require.Contains(t, controller.InitStatus()[0].LatestError().Message, "was stopped along with the pod")
+	})
+	t.Run("terminate_before_start", func(t *testing.T) {
+		for _, inits := range [][]InitContainerSpec{nil, {{Name: "init"}}} {
+			spec := PodSpec{
+				InitContainers: inits,
+				Containers: []ContainerSpec{
+					{
+						Name:           "main",
+						LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+						ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					},
+				},
+			}
+			initCtns, main := []Container{}, newBlockingContainer(false)
+			for range inits {
+				initCtns = append(initCtns, &mockContainer{})
+			}
+			controller, err := WithContainers(spec, initCtns, []Container{main})
+			require.NoError(t, err)
+
+			clock := clock.NewMock()
+			controller.Clock = clock
+			err = controller.Terminate(context.Background())
+			require.NoError(t, err)
+			err = controller.Start()
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			result, err := controller.Wait(ctx)
+			require.NoError(t, err)
+			require.Equal(t, PodResult{Phase: PodFailed, Reason: ReasonTerminated, ExitCode: 1}, result)
+			require.Equal(t, PodFailed, controller.Phase())
+			require.Equal(t, ReasonTerminated, controller.Reason())
+			require.False(t, main.Started())
+		}
+	})
+	t.Run("terminate_in_order", func(t *testing.T) {
+		grace := 2
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "db",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:                          "app",
+					LivenessProbe:                 LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe:                ReadinessProbeSpec{NewProbeSpec()},
+					TerminationGracePeriodSeconds: &grace,
+					DependsOn:                     []Dependency{{Name: "db"}},
+				},
+			},
+		}
+		db, app := newBlockingContainer(false), newBlockingContainer(false)
+		app.ignoreTerm = true
+		controller, err := WithContainers(spec, nil, []Container{db, app})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.True(t, app.Started())
+
+		terminated := make(chan error)
+		go func() { terminated <- controller.Terminate(context.Background()) }()
+		gosched()
+		timeTravel(clock, 1, time.Second)
+		require.Equal(t, []int{int(syscall.SIGTERM)}, app.Signals())
+		require.Empty(t, db.Signals())
+
+		timeTravel(clock, 2, time.Second)
+		require.NoError(t, <-terminated)
+		require.Equal(t, []int{int(syscall.SIGTERM)}, db.Signals())
+
+		statuses := controller.Status()
+		require.Equal(t, 1, statuses[0].LastTermination().Tier)
+		require.Equal(t, 0, statuses[1].LastTermination().Tier)
+		require.Equal(t, 2*time.Second, statuses[1].LastTermination().TierDuration)
+		require.True(t, statuses[1].LastTermination().Killed)
+	})
+	t.Run("shutdown_order", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{Name: "a"},
+				{Name: "b", DependsOn: []Dependency{{Name: "a"}}},
+				{Name: "c"},
+			},
+			ShutdownOrder: [][]string{{"a"}, {"b"}},
+		}
+		controller, err := WithContainers(spec, nil, []Container{&mockContainer{}, &mockContainer{}, &mockContainer{}})
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"a"}, {"b"}, {"c"}}, controller.shutdownTiers())
+
+		spec.ShutdownOrder = nil
+		controller, err = WithContainers(spec, nil, []Container{&mockContainer{}, &mockContainer{}, &mockContainer{}})
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"b"}, {"a", "c"}}, controller.shutdownTiers())
+	})
+	t.Run("invalid_shutdown_order", func(t *testing.T) {
+		spec := PodSpec{
+			Containers:    []ContainerSpec{{Name: "a"}},
+			ShutdownOrder: [][]string{{"a"}, {"a"}},
+		}
+		_, err := WithContainers(spec, nil, []Container{&mockContainer{}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "more than once")
+
+		spec.ShutdownOrder = [][]string{{"b"}}
+		_, err = WithContainers(spec, nil, []Container{&mockContainer{}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unknown container b")
+	})
+	t.Run("post_start_failure", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					Lifecycle: Lifecycle{
+						PostStart: &LifecycleHandler{Action: NewProbeSpec().setExec("warmup").Action},
+					},
+				},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		ctn.exec = func(string, ...string) (int, error) { return 1, nil }
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.True(t, ctn.Killed())
+		status := controller.Status()[0]
+		require.Equal(t, Failed, status.LastState())
+		require.Contains(t, status.LatestErrors[0].Message, "postStart hook of container main failed")
+		require.Equal(t, PodFailed, controller.Phase())
+	})
+	t.Run("pre_stop", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					Lifecycle: Lifecycle{
+						PreStop: &LifecycleHandler{Action: NewProbeSpec().setExec("deregister").Action},
+					},
+				},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		hooked := make(chan []int, 1)
+		ctn.exec = func(string, ...string) (int, error) {
+			hooked <- ctn.Signals()
+			return 0, nil
+		}
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.NoError(t, controller.Terminate(context.Background()))
+		require.Empty(t, <-hooked)
+		require.Equal(t, []int{int(syscall.SIGTERM)}, ctn.Signals())
+	})
+	t.Run("pre_stop_timeout", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					Lifecycle: Lifecycle{
+						PreStop: &LifecycleHandler{
+							Action:         NewProbeSpec().setExec("flush").Action,
+							TimeoutSeconds: 1,
+						},
+					},
+				},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		blocked := make(chan struct{})
+		defer close(blocked)
+		ctn.exec = func(string, ...string) (int, error) {
+			<-blocked
+			return 0, nil
+		}
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		terminated := make(chan error)
+		go func() { terminated <- controller.Terminate(context.Background()) }()
+		gosched()
+		timeTravel(clock, 2, time.Second)
+
+		require.NoError(t, <-terminated)
+		require.Equal(t, []int{int(syscall.SIGTERM)}, ctn.Signals())
+		status := controller.Status()[0]
+		require.Equal(t, "preStop hook of container main timed out after 1s", status.LatestError().Message)
+	})
+	t.Run("startup_probe", func(t *testing.T) {
+		startup := NewProbeSpec().setExec("boot")
+		startup.FailureThreshold = 3
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec().setExec("live")},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					StartupProbe:   &StartupProbeSpec{startup},
+				},
+			},
+		}
+		var lock sync.Mutex
+		booted, probed := false, false
+		ctn := newBlockingContainer(false)
+		ctn.exec = func(program string, arguments ...string) (int, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			if program == "live" {
+				probed = true
+			} else if !booted {
+				return 1, nil
+			}
+			return 0, nil
+		}
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		controller.getInfo("main").probes.Startup.(*LongLivedProbe).Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.Equal(t, Started, controller.Status()[0].LastState())
+		lock.Lock()
+		require.False(t, probed)
+		booted = true
+		lock.Unlock()
+
+		timeTravel(clock, 5, time.Second)
+		require.Equal(t, Healthy, controller.Status()[0].LastState())
+		lock.Lock()
+		require.True(t, probed)
+		lock.Unlock()
+	})
+	t.Run("startup_probe_failure", func(t *testing.T) {
+		startup := NewProbeSpec().setExec("boot")
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					StartupProbe:   &StartupProbeSpec{startup},
+				},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		ctn.exec = func(string, ...string) (int, error) { return 1, nil }
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 4, time.Second)
+		require.Equal(t, Terminal, controller.Status()[0].LastState())
+		require.Equal(t, []int{int(syscall.SIGKILL)}, ctn.Signals())
+		require.False(t, controller.Healthy())
+	})
+	t.Run("ready", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec().setExec("ready")},
+				},
+				{
+					Name:           "proxy",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec().setExec("ready")},
+					Role:           RoleSidecar,
+				},
+			},
+			MinReadySeconds: 5,
+		}
+		main, proxy := newBlockingContainer(false), newBlockingContainer(false)
+		proxy.exec = func(string, ...string) (int, error) { return 1, nil }
+		controller, err := WithContainers(spec, nil, []Container{main, proxy})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+		require.False(t, controller.Ready())
+
+		timeTravel(clock, 4, time.Second)
+		statuses := controller.Status()
+		require.True(t, statuses[0].Ready.Status)
+		require.False(t, statuses[1].Ready.Status)
+		require.False(t, controller.Ready())
+		require.True(t, controller.Healthy())
+
+		timeTravel(clock, 5, time.Second)
+		require.True(t, controller.Ready())
+
+		main.Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+		require.False(t, statuses[0].Ready.Status)
+		require.False(t, controller.Ready())
+	})
+	t.Run("ready_recovers", func(t *testing.T) {
+		readiness := NewProbeSpec().setExec("ready")
+		readiness.PeriodSeconds = 1
+		readiness.FailureThreshold = 2
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{readiness},
+				},
+			},
+		}
+		var lock sync.Mutex
+		ready := true
+		setReady := func(r bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			ready = r
+		}
+		ctn := newBlockingContainer(false)
+		ctn.exec = func(string, ...string) (int, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			if ready {
+				return 0, nil
+			}
+			return 1, nil
+		}
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		controller.getInfo("main").probes.Readiness.(*LongLivedProbe).Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.True(t, controller.Ready())
+
+		setReady(false)
+		timeTravel(clock, 4, time.Second)
+		require.False(t, controller.Ready())
+		require.True(t, controller.Healthy())
+
+		setReady(true)
+		timeTravel(clock, 3, time.Second)
+		require.True(t, controller.Ready())
+		require.Equal(t, Healthy, controller.Status()[0].LastState())
+	})
+	t.Run("quorum_health_policy", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "worker-1",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "worker-2",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "worker-3",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+			HealthPolicy: HealthPolicySpec{Type: HealthPolicyQuorum, Quorum: 2},
+		}
+		workers := []Container{
+			newBlockingContainer(false),
+			newBlockingContainer(false),
+			newBlockingContainer(false),
+		}
+		controller, err := WithContainers(spec, nil, workers)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.True(t, controller.Healthy())
+
+		workers[0].Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+		require.True(t, controller.Healthy())
+
+		workers[1].Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+		require.False(t, controller.Healthy())
+	})
+	t.Run("degraded", func(t *testing.T) {
+		liveness := NewProbeSpec().setExec("scrape")
+		liveness.FailureThreshold = 3
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "exporter",
+					LivenessProbe:  LivenessProbeSpec{liveness},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					Optional:       true,
+				},
+			},
+		}
+		main, exporter := newBlockingContainer(false), newBlockingContainer(false)
+		exporter.exec = func(string, ...string) (int, error) { return 1, nil }
+		controller, err := WithContainers(spec, nil, []Container{main, exporter})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.Equal(t, Failing, controller.Status()[1].LastState())
+		report := controller.Health()
+		require.Equal(t, PodDegraded, report.Health)
+		require.Equal(t, []string{"exporter"}, report.Containers)
+		require.True(t, controller.Healthy())
+
+		exporter.Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+		require.Equal(t, PodDegraded, controller.Health().Health)
+		require.True(t, controller.Healthy())
+
+		main.Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+		report = controller.Health()
+		require.Equal(t, PodUnhealthy, report.Health)
+		require.Equal(t, []string{"main"}, report.Containers)
+		require.False(t, controller.Healthy())
+	})
+	t.Run("job_succeeds", func(t *testing.T) {
+		spec := PodSpec{
+			Mode: PodModeJob,
+			Containers: []ContainerSpec{
+				{
+					Name:           "a",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "b",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		a, b := newBlockingContainer(false), newBlockingContainer(false)
+		controller, err := WithContainers(spec, nil, []Container{a, b})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		a.Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = controller.Wait(ctx)
+		require.Error(t, err)
+
+		b.Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+		result, err := controller.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, PodSucceeded, result.Phase)
+		require.Equal(t, 0, result.ExitCode)
+	})
+	t.Run("job_sidecar_terminated_once", func(t *testing.T) {
+		spec := PodSpec{
+			Mode: PodModeJob,
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "proxy",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					Role:           RoleSidecar,
+					Lifecycle: Lifecycle{
+						PreStop: &LifecycleHandler{Action: Action{Exec: &[]string{"drain"}}},
+					},
+				},
+			},
+		}
+
+		// The preStop hook of the sidecar blocks until it gets released, so that both the
+		// sidecar termination and the job completion get to terminate the sidecar.
+		var lock sync.Mutex
+		drains := 0
+		release := make(chan struct{})
+		main, sidecar := newBlockingContainer(false), newBlockingContainer(false)
+		sidecar.exec = func(string, ...string) (int, error) {
+			lock.Lock()
+			drains++
+			lock.Unlock()
+			<-release
+			return 0, nil
+		}
+		controller, err := WithContainers(spec, nil, []Container{main, sidecar})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		main.Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+		close(release)
+		timeTravel(clock, 2, time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		result, err := controller.Wait(ctx)
+		require.NoError(t, err)
+		require.Equal(t, PodSucceeded, result.Phase)
+		require.Equal(t, []int{int(syscall.SIGTERM)}, sidecar.Signals())
+		lock.Lock()
+		defer lock.Unlock()
+		require.Equal(t, 1, drains)
+	})
+	t.Run("job_fails", func(t *testing.T) {
+		spec := PodSpec{
+			Mode: PodModeJob,
+			Containers: []ContainerSpec{
+				{
+					Name:           "a",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "b",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		a, b := newBlockingContainer(false), newBlockingContainer(false)
+		a.exitErr = exitError(3)
+		controller, err := WithContainers(spec, nil, []Container{a, b})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		a.Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+
+		result, err := controller.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, PodFailed, result.Phase)
+		require.Equal(t, 3, result.ExitCode)
+		require.Equal(t, "container a failed with exit code 3", result.Reason)
+
+		// The rest of the job is terminated by the time Wait returns.
+		require.True(t, b.Killed())
+		require.Equal(t, []int{int(syscall.SIGTERM)}, b.Signals())
+		require.Equal(t, PodFailed, controller.Phase())
+		require.Equal(t, Finished, controller.Status()[1].LastState())
+	})
+	t.Run("job_waits_for_termination", func(t *testing.T) {
+		grace := 5
+		spec := PodSpec{
+			Mode: PodModeJob,
+			Containers: []ContainerSpec{
+				{
+					Name:           "a",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:                          "b",
+					LivenessProbe:                 LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe:                ReadinessProbeSpec{NewProbeSpec()},
+					TerminationGracePeriodSeconds: &grace,
+				},
+			},
+		}
+		a, b := newBlockingContainer(false), newBlockingContainer(false)
+		a.exitErr = exitError(1)
+		b.ignoreTerm = true
+		controller, err := WithContainers(spec, nil, []Container{a, b})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		a.Kill(int(syscall.SIGKILL))
+		timeTravel(clock, 2, time.Second)
+
+		// b ignores its stop signal, the job cannot complete before its grace period.
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = controller.Wait(ctx)
+		require.Error(t, err)
+		require.False(t, b.Killed())
+
+		timeTravel(clock, 6, time.Second)
+		result, err := controller.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, PodFailed, result.Phase)
+		require.True(t, b.Killed())
+		require.Equal(t, []int{int(syscall.SIGTERM), int(syscall.SIGKILL)}, b.Signals())
+	})
+	t.Run("active_deadline", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+			ActiveDeadlineSeconds: 5,
+		}
+		ctn := newBlockingContainer(false)
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 4, time.Second)
+		require.Equal(t, PodRunning, controller.Phase())
+		require.Equal(t, "", controller.Reason())
+
+		timeTravel(clock, 2, time.Second)
+		require.Equal(t, PodFailed, controller.Phase())
+		require.Equal(t, ReasonDeadlineExceeded, controller.Reason())
+		require.Equal(t, []int{int(syscall.SIGTERM)}, ctn.Signals())
+		require.False(t, controller.Healthy())
+
+		result, err := controller.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, PodFailed, result.Phase)
+		require.Equal(t, ReasonDeadlineExceeded, result.Reason)
+	})
+	t.Run("active_deadline_terminates_first", func(t *testing.T) {
+		grace := 5
+		spec := PodSpec{
+			Mode: PodModeJob,
+			Containers: []ContainerSpec{
+				{
+					Name:                          "main",
+					LivenessProbe:                 LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe:                ReadinessProbeSpec{NewProbeSpec()},
+					TerminationGracePeriodSeconds: &grace,
+				},
+			},
+			ActiveDeadlineSeconds: 3,
+		}
+		ctn := newBlockingContainer(false)
+		ctn.ignoreTerm = true
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 4, time.Second)
+		require.Equal(t, ReasonDeadlineExceeded, controller.Reason())
+
+		// The container ignores its stop signal, the pod cannot complete before its
+		// grace period.
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = controller.Wait(ctx)
+		require.Error(t, err)
+		require.False(t, ctn.Killed())
+
+		timeTravel(clock, 6, time.Second)
+		result, err := controller.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, ReasonDeadlineExceeded, result.Reason)
+		require.True(t, ctn.Killed())
+	})
+	t.Run("active_deadline_init", func(t *testing.T) {
+		spec := PodSpec{
+			InitContainers: []InitContainerSpec{
+				{Name: "init"},
+			},
+			ActiveDeadlineSeconds: 2,
+		}
+		ctn := newBlockingContainer(false)
+		controller, err := WithContainers(spec, []Container{ctn}, nil)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.True(t, ctn.Killed())
+		require.Equal(t, PodFailed, controller.Phase())
+		require.Contains(t, controller.InitStatus()[0].LatestError().Message, "exceeded its deadline")
+	})
+	t.Run("progress_deadline", func(t *testing.T) {
+		startup := NewProbeSpec().setExec("boot")
+		startup.FailureThreshold = 100
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					StartupProbe:   &StartupProbeSpec{startup},
+				},
+			},
+			ProgressDeadlineSeconds: 5,
+		}
+		ctn := newBlockingContainer(false)
+		ctn.exec = func(string, ...string) (int, error) { return 1, nil }
+		controller, err := WithContainers(spec, nil, []Container{ctn})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.Equal(t, Started, controller.Status()[0].LastState())
+		require.True(t, controller.Healthy())
+
+		timeTravel(clock, 3, time.Second)
+		require.Equal(t, Started, controller.Status()[0].LastState())
+		require.True(t, controller.Status()[0].ProgressStalled)
+		require.False(t, controller.Healthy())
+	})
+	t.Run("init_deadline", func(t *testing.T) {
+		spec := PodSpec{
+			InitContainers: []InitContainerSpec{
+				{Name: "init", ActiveDeadlineSeconds: 2},
+			},
+		}
+		ctn := newBlockingContainer(false)
+		controller, err := WithContainers(spec, []Container{ctn}, nil)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+		gosched()
+		require.Equal(t, PodInitializing, controller.Phase())
+
+		timeTravel(clock, 3, time.Second)
+
+		require.Equal(t, PodFailed, controller.Phase())
+		require.False(t, controller.Healthy())
+		require.True(t, ctn.Killed())
+
+		statuses := controller.InitStatus()
+		require.Lenf(t, statuses, 1, "should only have 1 init status")
+		require.Equal(t, InitFailed, statuses[0].LastState())
+		require.Equal(t, 2*time.Second, statuses[0].Duration(clock.Now()))
+		require.Contains(t, statuses[0].LatestError().Message, "deadline")
+	})
+	t.Run("init_retries", func(t *testing.T) {
+		spec := PodSpec{
+			InitContainers: []InitContainerSpec{
+				{
+					Name:    "init",
+					Retries: 2,
+					Backoff: BackoffSpec{InitialDelaySeconds: 1},
+				},
+			},
+		}
+		var lock sync.Mutex
+		bootstrapped := 0
+		bootstrapper := func(oci.Spec, map[string]interface{}) (Container, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			bootstrapped++
+			if bootstrapped < 3 {
+				return &mockContainer{exitErr: errors.New("exit status 1")}, nil
+			}
+			return &mockContainer{}, nil
+		}
+		controller, err := WithBootstrapper(spec, bootstrapper)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+		gosched()
+		timeTravel(clock, 5, time.Second)
+
+		require.Equal(t, PodSucceeded, controller.Phase())
+		lock.Lock()
+		defer lock.Unlock()
+		require.Equal(t, 3, bootstrapped)
+
+		statuses := controller.InitStatus()
+		require.Lenf(t, statuses, 1, "should only have 1 init status")
+		require.Equal(t, InitSucceeded, statuses[0].LastState())
+		require.Equal(t, 2, statuses[0].Restarts)
+		require.Len(t, statuses[0].LatestErrors, 2)
+	})
+	t.Run("init_retries_exhausted", func(t *testing.T) {
+		spec := PodSpec{
+			InitContainers: []InitContainerSpec{
+				{
+					Name:    "init",
+					Retries: 1,
+					Backoff: BackoffSpec{InitialDelaySeconds: 1},
+				},
+				{Name: "next"},
+			},
+		}
+		bootstrapper := func(oci.Spec, map[string]interface{}) (Container, error) {
+			return &mockContainer{exitErr: errors.New("exit status 1")}, nil
+		}
+		controller, err := WithBootstrapper(spec, bootstrapper)
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+		gosched()
+		timeTravel(clock, 5, time.Second)
+
+		require.Equal(t, PodFailed, controller.Phase())
+
+		statuses := controller.InitStatus()
+		require.Lenf(t, statuses, 2, "should have 2 init statuses")
+		require.Equal(t, InitFailed, statuses[0].LastState())
+		require.Equal(t, "exit status 1", statuses[0].LatestError().Message)
+		require.Equal(t, 1, statuses[0].Restarts)
+		require.Equal(t, InitPending, statuses[1].LastState())
+	})
+	t.Run("sidecar", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+				{
+					Name:           "proxy",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					RestartPolicy:  RestartAlways,
+					Role:           RoleSidecar,
+				},
+			},
+		}
+		main := &mockContainer{running: make(chan struct{})}
+		sidecar := newBlockingContainer(false)
+		sidecar.gate = make(chan struct{})
+		controller, err := WithContainers(spec, nil, []Container{main, sidecar})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		// The main container waits for the sidecar to start.
+		timeTravel(clock, 3, time.Second)
+		require.False(t, main.Started())
+
+		close(sidecar.gate)
+		timeTravel(clock, 3, time.Second)
+		require.True(t, main.Started())
+		require.Equal(t, PodRunning, controller.Phase())
+
+		// Once the main container exits the sidecar gets terminated.
+		close(main.running)
+		timeTravel(clock, 3, time.Second)
+		require.Equal(t, []int{int(syscall.SIGTERM)}, sidecar.Signals())
+		require.Equal(t, PodSucceeded, controller.Phase())
+
+		statuses := controller.Status()
+		require.Equal(t, Finished, statuses[0].LastState())
+		require.Equal(t, 0, statuses[1].Restarts)
+	})
+	t.Run("invalid_actions", func(t *testing.T) {
+		readiness := NewProbeSpec()
+		readiness.HTTPGet = &HTTPGetAction{Port: 8080, ExpectedBodyRegexp: "("}
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "main",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{readiness},
+				},
+			},
+		}
+		_, err := WithContainers(spec, nil, []Container{&mockContainer{}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid expected body regexp")
+
+		spec.Containers[0].ReadinessProbe = ReadinessProbeSpec{NewProbeSpec()}
+		spec.Containers[0].Lifecycle.PreStop = &LifecycleHandler{
+			Action: Action{HTTPGet: &HTTPGetAction{Port: 8080, RedirectPolicy: "Sometimes"}},
+		}
+		_, err = WithContainers(spec, nil, []Container{&mockContainer{}})
+		require.EqualError(t, err, `container main: invalid preStop hook: unknown redirect policy "Sometimes"`)
+
+		spec.Containers[0].Lifecycle.PreStop = nil
+		spec.Containers[0].LivenessProbe = LivenessProbeSpec{NewProbeSpec()}
+		spec.Containers[0].LivenessProbe.Exec = &[]string{}
+		_, err = WithContainers(spec, nil, []Container{&mockContainer{}})
+		require.EqualError(t, err, "container main: exec action has no command")
+	})
+	t.Run("only_sidecars", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{Name: "proxy", Role: RoleSidecar},
+			},
+		}
+		_, err := WithContainers(spec, nil, []Container{&mockContainer{}})
+		require.Error(t, err)
+	})
+	t.Run("depends_on", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "app",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					DependsOn:      []Dependency{{Name: "migrate", Condition: DependencyCompleted}},
+				},
+				{
+					Name:           "migrate",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		app := newBlockingContainer(false)
+		migrate := &mockContainer{running: make(chan struct{})}
+		controller, err := WithContainers(spec, nil, []Container{app, migrate})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.False(t, app.Started())
+		statuses := controller.Status()
+		require.Equal(t, Waiting, statuses[0].LastState())
+		require.Equal(t, "waiting for migrate to complete", statuses[0].WaitingReason)
+		require.True(t, controller.Healthy())
+
+		close(migrate.running)
+		timeTravel(clock, 3, time.Second)
+		require.True(t, app.Started())
+		require.Equal(t, Finished, statuses[1].LastState())
+		require.NotEqual(t, Waiting, statuses[0].LastState())
+		require.Equal(t, "", statuses[0].WaitingReason)
+	})
+	t.Run("depends_on_terminating", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name:           "app",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+					DependsOn:      []Dependency{{Name: "migrate", Condition: DependencyCompleted}},
+				},
+				{
+					Name:           "migrate",
+					LivenessProbe:  LivenessProbeSpec{NewProbeSpec()},
+					ReadinessProbe: ReadinessProbeSpec{NewProbeSpec()},
+				},
+			},
+		}
+		app := newBlockingContainer(false)
+		migrate := newBlockingContainer(false)
+		controller, err := WithContainers(spec, nil, []Container{app, migrate})
+		require.NoError(t, err)
+
+		clock := clock.NewMock()
+		controller.Clock = clock
+		err = controller.Start()
+		require.NoError(t, err)
+
+		timeTravel(clock, 3, time.Second)
+		require.False(t, app.Started())
+
+		// Terminating the pod makes migrate exit with a 0 status code, which must not
+		// launch app.
+		err = controller.Terminate(context.Background())
+		require.NoError(t, err)
+		timeTravel(clock, 3, time.Second)
+		require.False(t, app.Started())
+	})
+	t.Run("dependency_cycle", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{Name: "a", DependsOn: []Dependency{{Name: "b"}}},
+				{Name: "b", DependsOn: []Dependency{{Name: "c", Condition: DependencyReady}}},
+				{Name: "c", DependsOn: []Dependency{{Name: "a", Condition: DependencyHealthy}}},
+			},
+		}
+		_, err := WithContainers(spec, nil, []Container{&mockContainer{}, &mockContainer{}, &mockContainer{}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "a -> b -> c -> a")
+	})
+	t.Run("sidecar_dependency_cycle", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{Name: "main"},
+				{Name: "proxy", Role: RoleSidecar, DependsOn: []Dependency{{Name: "main"}}},
+			},
+		}
+		_, err := WithContainers(spec, nil, []Container{&mockContainer{}, &mockContainer{}})
+		require.Error(t, err)
+	})
+	t.Run("unknown_dependency", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{Name: "main", DependsOn: []Dependency{{Name: "db"}}},
+			},
+		}
+		_, err := WithContainers(spec, nil, []Container{&mockContainer{}})
+		require.Error(t, err)
+	})
+	t.Run("unknown_restart_policy", func(t *testing.T) {
+		spec := PodSpec{
+			Containers: []ContainerSpec{
+				{
+					Name: "main",
+					Spec: oci.Spec{
+						Process: &oci.Process{
+							Args: []string{"true"},
+						},
+					},
+					RestartPolicy: "Sometimes",
+				},
+			},
+		}
+		_, err := NewPodController(spec, "./bins/testing.so")
+		require.Error(t, err)
+	})
 	t.Run("single_healthy_then_unhealthy", func(t *testing.T) {
 		// TODO: write tests
 	})
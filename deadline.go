@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"time"
+)
+
+// ReasonDeadlineExceeded is the reason of a pod that failed because it ran for longer
+// than its ActiveDeadlineSeconds.
+const ReasonDeadlineExceeded = "DeadlineExceeded"
+
+// Reason returns why the pod failed when it is for a pod-wide cause, like its active
+// deadline, and the empty string otherwise.
+func (c *controller) Reason() string {
+	c.Lock()
+	defer c.Unlock()
+	return c.reason
+}
+
+// enforceDeadline terminates the pod and marks it as failed if it has not completed
+// by the end of its active deadline. It runs in the background from the start of the
+// controller, and is the one to complete the pod once the deadline is exceeded.
+func (c *controller) enforceDeadline() {
+	if c.activeDeadline <= 0 {
+		return
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		select {
+		case <-c.stop:
+			return
+		case <-c.completed:
+			return
+		case <-c.Clock.After(c.activeDeadline):
+		}
+
+		c.Lock()
+		c.reason = ReasonDeadlineExceeded
+		close(c.exceeded)
+		c.Unlock()
+
+		// The pod only completes once it is terminated, so that nothing is left running
+		// when Wait returns.
+		ctx, cancel := c.stopContext()
+		defer cancel()
+		c.Terminate(ctx)
+		c.complete(PodResult{Phase: PodFailed, Reason: ReasonDeadlineExceeded, ExitCode: 1})
+	}()
+}
+
+// progressStalled returns true if the container has been Started for longer than the
+// progress deadline of the pod without becoming healthy.
+func (c *controller) progressStalled(status *ContainerStatus, now time.Time) bool {
+	return c.progressDeadline > 0 && status.LastState() == Started && status.Uptime(now) >= c.progressDeadline
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DependencyCondition is the condition a container waits for on one of its dependencies
+// before it gets launched.
+type DependencyCondition string
+
+const (
+	DependencyStarted   DependencyCondition = "started"   // The dependency was started
+	DependencyHealthy   DependencyCondition = "healthy"   // The liveness probe of the dependency is healthy
+	DependencyReady     DependencyCondition = "ready"     // The readiness probe of the dependency is healthy
+	DependencyCompleted DependencyCondition = "completed" // The dependency exited with a 0 status code
+)
+
+// Dependency declares that a container can only be launched once the container with
+// the given name meets the condition. The condition defaults to started.
+type Dependency struct {
+	Name      string
+	Condition DependencyCondition
+}
+
+func (dep Dependency) condition() DependencyCondition {
+	if dep.Condition == "" {
+		return DependencyStarted
+	}
+	return dep.Condition
+}
+
+// dependencies returns the dependencies of the container, including the implicit ones:
+// main containers depend on every sidecar being started.
+func (c *controller) dependencies(name string) []Dependency {
+	spec := c.getInfo(name).spec
+	deps := append([]Dependency{}, spec.DependsOn...)
+	if spec.isSidecar() {
+		return deps
+	}
+	for _, other := range c.MainOrder {
+		if c.getInfo(other).spec.isSidecar() {
+			deps = append(deps, Dependency{Name: other, Condition: DependencyStarted})
+		}
+	}
+	return deps
+}
+
+// validateDependencies makes sure that every dependency refers to a known container with
+// a known condition, and that the dependencies do not form a cycle.
+func (c *controller) validateDependencies() error {
+	for _, name := range c.MainOrder {
+		for _, dep := range c.getInfo(name).spec.DependsOn {
+			if _, ok := c.MainInfos[dep.Name]; !ok {
+				return fmt.Errorf("container %s depends on unknown container %s", name, dep.Name)
+			}
+			switch dep.condition() {
+			case DependencyStarted, DependencyHealthy, DependencyReady, DependencyCompleted:
+			default:
+				return fmt.Errorf("container %s depends on %s with unknown condition %q",
+					name, dep.Name, dep.Condition)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		path = append(path, name)
+		switch marks[name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle between containers: %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		marks[name] = visiting
+		for _, dep := range c.dependencies(name) {
+			if err := visit(dep.Name, path); err != nil {
+				return err
+			}
+		}
+		marks[name] = visited
+		return nil
+	}
+	for _, name := range c.MainOrder {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockedBy returns the reason why the container cannot be launched yet, or the empty
+// string if all of its dependencies are met.
+func (c *controller) blockedBy(name string) string {
+	for _, dep := range c.dependencies(name) {
+		if !c.isLaunched(dep.Name) {
+			return fmt.Sprintf("waiting for %s to be launched", dep.Name)
+		}
+		info := c.getInfo(dep.Name)
+		switch dep.condition() {
+		case DependencyStarted:
+			if !info.probes.Exit.Waiting() {
+				return fmt.Sprintf("waiting for %s to start", dep.Name)
+			}
+		case DependencyHealthy:
+			if info.status.LastState() != Healthy {
+				return fmt.Sprintf("waiting for %s to be healthy", dep.Name)
+			}
+		case DependencyReady:
+			if ready, _ := info.probes.Readiness.Healthy(); !ready {
+				return fmt.Sprintf("waiting for %s to be ready", dep.Name)
+			}
+		case DependencyCompleted:
+			if info.status.LastState() != Finished {
+				return fmt.Sprintf("waiting for %s to complete", dep.Name)
+			}
+		}
+	}
+	return ""
+}
+
+// launchPending launches the containers whose dependencies are met and that have not
+// been launched yet. The other ones are marked as waiting along with the reason. Nothing
+// gets launched once the pod is terminating, nor are the containers already terminated.
+func (c *controller) launchPending() {
+	if c.isTerminating() {
+		return
+	}
+	for _, name := range c.MainOrder {
+		if c.isLaunched(name) || c.isTerminated(name) {
+			continue
+		}
+		if reason := c.blockedBy(name); reason != "" {
+			c.getInfo(name).status.RecordWaiting(reason)
+			continue
+		}
+		c.launch(name)
+	}
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"sync"
 )
 
@@ -9,7 +10,8 @@ import (
 // code was not 0 it will return UNHEALTHY forever.
 // The exitprobe will never go back to a healthy state after
 // reaching an unhealthy state and stopping.
-// Since we cannot preempt a goroutine, the Stop method is a no-op.
+// Since we cannot preempt a goroutine, the Stop method stops the check
+// which kills the process it is waiting on.
 type ExitProbe struct {
 	sync.Mutex
 
@@ -18,6 +20,7 @@ type ExitProbe struct {
 	hasStarted bool
 	success    bool
 	err        error
+	done       chan struct{}
 }
 
 func NewExitProbe(check *AsyncCheck) *ExitProbe {
@@ -30,10 +33,13 @@ func (p *ExitProbe) Start() {
 	p.Lock()
 	p.isRunning = true
 	p.hasStarted = true
+	p.done = make(chan struct{})
+	done := p.done
 	p.Unlock()
 
 	go func() {
-		success, err := p.Check.Run()
+		defer close(done)
+		success, err := p.Check.Run(context.Background())
 		p.Lock()
 		p.success, p.err = success, err
 		p.isRunning = false
@@ -66,4 +72,17 @@ func (p *ExitProbe) Started() bool {
 	return p.hasStarted
 }
 
-func (p *ExitProbe) Stop() {}
+func (p *ExitProbe) Stop() {
+	if p.Running() {
+		p.Check.Stop()
+	}
+}
+
+func (p *ExitProbe) Done() <-chan struct{} {
+	p.Lock()
+	defer p.Unlock()
+	if p.done == nil {
+		return closedChan()
+	}
+	return p.done
+}
@@ -62,6 +62,28 @@ func TestExitProbe(t *testing.T) {
 		timeTravel(clock, 10, 1*time.Second)
 		require.False(t, probe.Running())
 	})
+	t.Run("stop_kills", func(t *testing.T) {
+		exited := make(chan struct{})
+		check := NewAsyncCheck(func() error { return nil }, func() error {
+			<-exited
+			return fmt.Errorf("killed")
+		})
+		check.Kill = func() error {
+			close(exited)
+			return nil
+		}
+		probe := NewExitProbe(check)
+		probe.Start()
+		gosched()
+		require.True(t, probe.Running())
+
+		probe.Stop()
+		<-probe.Done()
+		require.False(t, probe.Running())
+		healthy, err := probe.Healthy()
+		require.False(t, healthy)
+		require.Error(t, err)
+	})
 	t.Run("healthy_while_running", func(t *testing.T) {
 		clock := clock.NewMock()
 		check := newMockAsyncCheck(clock, 10*time.Second, 10*time.Second, nil, nil)
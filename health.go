@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PodHealth refines the healthy bit of the pod. A degraded pod is still healthy, it
+// should not be rescheduled, but some of its containers need attention.
+type PodHealth int
+
+const (
+	PodHealthy   PodHealth = iota // When the health policy is met and all the containers are healthy
+	PodDegraded                   // When the health policy is met but some containers, possibly optional ones, are not healthy
+	PodUnhealthy                  // When the health policy of the pod is not met
+)
+
+func (health PodHealth) String() string {
+	switch health {
+	case PodHealthy:
+		return "HEALTHY"
+	case PodDegraded:
+		return "DEGRADED"
+	case PodUnhealthy:
+		return "UNHEALTHY"
+	}
+	return "UNKNOWN"
+}
+
+// HealthReport is the health of the pod, along with the reason for it and the names
+// of the containers that keep the pod from being fully healthy.
+type HealthReport struct {
+	Health     PodHealth
+	Reason     string
+	Containers []string
+}
+
+// Healthy returns the healthy bit of the report, which is only false when the pod
+// is unhealthy.
+func (report HealthReport) Healthy() bool {
+	return report.Health != PodUnhealthy
+}
+
+// Health aggregates the statuses of the containers through the health policy of the pod,
+// leaving the optional containers out. A pod that meets its health policy is degraded
+// if any of its containers is failing or not healthy. The containers in a crash loop are
+// not healthy, it is up to the policy to decide whether the pod can do without them.
+func (c *controller) Health() HealthReport {
+	if c.initFailed() {
+		return HealthReport{Health: PodUnhealthy, Reason: "init sequence failed", Containers: []string{}}
+	} else if reason := c.Reason(); reason != "" {
+		return HealthReport{Health: PodUnhealthy, Reason: reason, Containers: []string{}}
+	}
+
+	required, degraded, unhealthy := []*ContainerStatus{}, []string{}, []string{}
+	for _, name := range c.MainOrder {
+		info := c.getInfo(name)
+		// Sidecars that were terminated because the main containers exited do not
+		// make the pod unhealthy.
+		if info.spec.isSidecar() && c.isTerminated(name) && !c.isTerminating() {
+			continue
+		}
+		if !info.spec.Optional {
+			required = append(required, info.status)
+			if !info.status.Healthy() {
+				unhealthy = append(unhealthy, name)
+			}
+		}
+		if !info.status.Healthy() || info.status.LastState() == Failing {
+			degraded = append(degraded, name)
+		}
+	}
+
+	if healthy, reason := c.HealthPolicy.Healthy(required); !healthy {
+		return HealthReport{Health: PodUnhealthy, Reason: reason, Containers: unhealthy}
+	} else if len(degraded) > 0 {
+		reason = fmt.Sprintf("containers are not fully healthy: %s", strings.Join(degraded, ", "))
+		return HealthReport{Health: PodDegraded, Reason: reason, Containers: degraded}
+	} else {
+		return HealthReport{Health: PodHealthy, Reason: reason, Containers: []string{}}
+	}
+}
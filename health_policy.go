@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A HealthPolicy aggregates the statuses of the containers of a pod into the health of
+// the pod, along with a reason explaining the verdict.
+type HealthPolicy interface {
+	Healthy(statuses []*ContainerStatus) (healthy bool, reason string)
+}
+
+var _ HealthPolicy = AllHealthy{}
+var _ HealthPolicy = AnyHealthy{}
+var _ HealthPolicy = QuorumHealthy{}
+var _ HealthPolicy = CriticalHealthy{}
+
+// AllHealthy deems the pod healthy if all of its containers are.
+type AllHealthy struct{}
+
+// Healthy implements HealthPolicy.Healthy.
+func (AllHealthy) Healthy(statuses []*ContainerStatus) (bool, string) {
+	for _, status := range statuses {
+		if !status.Healthy() {
+			return false, fmt.Sprintf("container %s is %v", status.Name, status.LastState())
+		}
+	}
+	return true, "all containers are healthy"
+}
+
+// AnyHealthy deems the pod healthy as long as one of its containers is.
+type AnyHealthy struct{}
+
+// Healthy implements HealthPolicy.Healthy.
+func (AnyHealthy) Healthy(statuses []*ContainerStatus) (bool, string) {
+	for _, status := range statuses {
+		if status.Healthy() {
+			return true, fmt.Sprintf("container %s is healthy", status.Name)
+		}
+	}
+	return false, "no container is healthy"
+}
+
+// QuorumHealthy deems the pod healthy if at least Quorum of its containers are.
+type QuorumHealthy struct {
+	Quorum int
+}
+
+// Healthy implements HealthPolicy.Healthy.
+func (policy QuorumHealthy) Healthy(statuses []*ContainerStatus) (bool, string) {
+	healthy := 0
+	for _, status := range statuses {
+		if status.Healthy() {
+			healthy++
+		}
+	}
+	reason := fmt.Sprintf("%d of %d containers are healthy, %d required", healthy, len(statuses), policy.Quorum)
+	return healthy >= policy.Quorum, reason
+}
+
+// CriticalHealthy deems the pod healthy if all of the named containers are, regardless
+// of the health of the other ones.
+type CriticalHealthy struct {
+	Names []string
+}
+
+// Healthy implements HealthPolicy.Healthy.
+func (policy CriticalHealthy) Healthy(statuses []*ContainerStatus) (bool, string) {
+	for _, status := range statuses {
+		if stringsContain(policy.Names, status.Name) && !status.Healthy() {
+			return false, fmt.Sprintf("critical container %s is %v", status.Name, status.LastState())
+		}
+	}
+	return true, fmt.Sprintf("critical containers are healthy: %s", strings.Join(policy.Names, ", "))
+}
+
+// HealthPolicyType selects one of the built-in health policies.
+type HealthPolicyType string
+
+const (
+	HealthPolicyAll      HealthPolicyType = "all"
+	HealthPolicyAny      HealthPolicyType = "any"
+	HealthPolicyQuorum   HealthPolicyType = "quorum"
+	HealthPolicyCritical HealthPolicyType = "critical"
+)
+
+// HealthPolicySpec selects the health policy of a pod, defaulting to all. Quorum is
+// only used by the quorum policy, and Critical by the critical one.
+type HealthPolicySpec struct {
+	Type     HealthPolicyType
+	Quorum   int
+	Critical []string
+}
+
+// Materialize returns the health policy, making sure that it applies to the given
+// containers.
+func (spec HealthPolicySpec) Materialize(names []string) (HealthPolicy, error) {
+	switch spec.Type {
+	case "", HealthPolicyAll:
+		return AllHealthy{}, nil
+	case HealthPolicyAny:
+		return AnyHealthy{}, nil
+	case HealthPolicyQuorum:
+		if spec.Quorum < 1 || spec.Quorum > len(names) {
+			return nil, fmt.Errorf("health policy quorum must be between 1 and %d, got %d", len(names), spec.Quorum)
+		}
+		return QuorumHealthy{Quorum: spec.Quorum}, nil
+	case HealthPolicyCritical:
+		if len(spec.Critical) == 0 {
+			return nil, fmt.Errorf("critical health policy lists no container")
+		}
+		for _, name := range spec.Critical {
+			if !stringsContain(names, name) {
+				return nil, fmt.Errorf("critical health policy lists unknown container %s", name)
+			}
+		}
+		return CriticalHealthy{Names: spec.Critical}, nil
+	}
+	return nil, fmt.Errorf("unknown health policy %q", spec.Type)
+}
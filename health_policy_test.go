@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newStatusInState(name string, state ContainerState) *ContainerStatus {
+	status := NewContainerStatus(name)
+	status.AddState(state)
+	return status
+}
+
+func TestHealthPolicy(t *testing.T) {
+	statuses := []*ContainerStatus{
+		newStatusInState("a", Healthy),
+		newStatusInState("b", Healthy),
+		newStatusInState("c", Terminal),
+	}
+	t.Run("all", func(t *testing.T) {
+		healthy, reason := AllHealthy{}.Healthy(statuses)
+		require.False(t, healthy)
+		require.Equal(t, "container c is TERMINAL", reason)
+
+		healthy, _ = AllHealthy{}.Healthy(statuses[:2])
+		require.True(t, healthy)
+	})
+	t.Run("any", func(t *testing.T) {
+		healthy, _ := AnyHealthy{}.Healthy(statuses)
+		require.True(t, healthy)
+
+		healthy, reason := AnyHealthy{}.Healthy(statuses[2:])
+		require.False(t, healthy)
+		require.Equal(t, "no container is healthy", reason)
+	})
+	t.Run("quorum", func(t *testing.T) {
+		healthy, reason := QuorumHealthy{Quorum: 2}.Healthy(statuses)
+		require.True(t, healthy)
+		require.Equal(t, "2 of 3 containers are healthy, 2 required", reason)
+
+		healthy, _ = QuorumHealthy{Quorum: 3}.Healthy(statuses)
+		require.False(t, healthy)
+	})
+	t.Run("critical", func(t *testing.T) {
+		healthy, _ := CriticalHealthy{Names: []string{"a", "b"}}.Healthy(statuses)
+		require.True(t, healthy)
+
+		healthy, reason := CriticalHealthy{Names: []string{"a", "c"}}.Healthy(statuses)
+		require.False(t, healthy)
+		require.Equal(t, "critical container c is TERMINAL", reason)
+	})
+	t.Run("materialize", func(t *testing.T) {
+		names := []string{"a", "b", "c"}
+		policy, err := HealthPolicySpec{}.Materialize(names)
+		require.NoError(t, err)
+		require.Equal(t, AllHealthy{}, policy)
+
+		policy, err = HealthPolicySpec{Type: HealthPolicyQuorum, Quorum: 2}.Materialize(names)
+		require.NoError(t, err)
+		require.Equal(t, QuorumHealthy{Quorum: 2}, policy)
+
+		_, err = HealthPolicySpec{Type: HealthPolicyQuorum, Quorum: 4}.Materialize(names)
+		require.Error(t, err)
+		_, err = HealthPolicySpec{Type: HealthPolicyCritical, Critical: []string{"d"}}.Materialize(names)
+		require.Error(t, err)
+		_, err = HealthPolicySpec{Type: "most"}.Materialize(names)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// HTTPGetAction sends an HTTP request to the port and succeeds if the status code of the
+// response is one of the success codes, 200 to 399 by default. The request is a GET unless
+// another method is given, and the expected body can be either a substring or a regular
+// expression of the beginning of the response. Redirects are followed unless the redirect
+// policy is None, in which case the redirect response itself gets checked.
+type HTTPGetAction struct {
+	Host        string
+	Path        string
+	Port        int
+	Scheme      string
+	Method      string
+	HTTPHeaders []HTTPHeader
+
+	SuccessCodes       []StatusCodeRange
+	ExpectedBody       string
+	ExpectedBodyRegexp string
+
+	TLS            *HTTPTLSConfig
+	RedirectPolicy HTTPRedirectPolicy
+}
+
+// HTTPTLSConfig holds the TLS settings of HTTPS requests. The certificate of the server is
+// verified against the CA file when there is one, against the roots of the host otherwise.
+// A client certificate is sent if both CertFile and KeyFile are set.
+type HTTPTLSConfig struct {
+	InsecureSkipVerify bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+}
+
+// HTTPRedirectPolicy tells whether HTTP checks follow redirects.
+type HTTPRedirectPolicy string
+
+const (
+	RedirectFollow HTTPRedirectPolicy = "Follow"
+	RedirectNone   HTTPRedirectPolicy = "None"
+)
+
+// check returns the HTTP check of the action, with a client that gives up after the timeout
+// if it is not 0. The client opens its connections with dial if it is not nil.
+func (action HTTPGetAction) check(timeout time.Duration, dial dialFunc) (HTTPCheck, error) {
+	host := net.JoinHostPort(action.Host, strconv.Itoa(action.Port))
+	check := NewHTTPCheck(host, action.Path)
+	if action.Scheme != "" {
+		check.Scheme = strings.ToLower(action.Scheme)
+	}
+	if action.Method != "" {
+		check.Method = strings.ToUpper(action.Method)
+	}
+	check.Headers = action.HTTPHeaders
+	check.SuccessRanges = action.SuccessCodes
+	check.ExpectedBody = action.ExpectedBody
+	if action.ExpectedBodyRegexp != "" {
+		re, err := regexp.Compile(action.ExpectedBodyRegexp)
+		if err != nil {
+			return check, errors.Wrapf(err, "invalid expected body regexp")
+		}
+		check.ExpectedBodyRegexp = re
+	}
+
+	var tlsConfig *tls.Config
+	if action.TLS != nil {
+		var err error
+		if tlsConfig, err = action.TLS.config(); err != nil {
+			return check, err
+		}
+	}
+	var client *http.Client
+	switch action.RedirectPolicy {
+	case "", RedirectFollow:
+		client = NewHTTPClient(timeout, tlsConfig, true)
+	case RedirectNone:
+		client = NewHTTPClient(timeout, tlsConfig, false)
+	default:
+		return check, fmt.Errorf("unknown redirect policy %q", action.RedirectPolicy)
+	}
+	if dial != nil {
+		client.Transport.(*http.Transport).DialContext = dial
+	}
+	check.Client = client
+	return check, nil
+}
+
+func (config HTTPTLSConfig) config() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CAFile != "" {
+		pem, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA file %s", config.CAFile)
+		}
+	}
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load client certificate %s", config.CertFile)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// httpGetSpec returns a probe spec that sends requests to the test server.
+func httpGetSpec(t *testing.T, server *httptest.Server, action HTTPGetAction) ProbeSpec {
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	action.Host = host
+	action.Port, err = strconv.Atoi(port)
+	require.NoError(t, err)
+
+	spec := NewProbeSpec()
+	spec.HTTPGet = &action
+	return spec
+}
+
+func runSpec(spec ProbeSpec) (bool, error) {
+	return spec.GetCheck(&mockContainer{}).Run(context.Background())
+}
+
+// writeKeyPair writes a self-signed certificate for 127.0.0.1 and its key to the directory,
+// and returns the paths of both files.
+func writeKeyPair(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	require.NoError(t, err)
+	return certFile, keyFile
+}
+
+func TestHTTPGetAction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/headers":
+			if r.Method != http.MethodHead || r.Header.Get("X-Probe") != "yes" || r.Host != "probe.local" {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		case "/redirect":
+			http.Redirect(w, r, "/missing", http.StatusFound)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/teapot":
+			w.WriteHeader(http.StatusTeapot)
+		default:
+			w.Write([]byte(`{"status": "ok", "version": "1.2.3"}`))
+		}
+	}))
+	defer server.Close()
+
+	t.Run("default", func(t *testing.T) {
+		success, err := runSpec(httpGetSpec(t, server, HTTPGetAction{Path: "/"}))
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("method_and_headers", func(t *testing.T) {
+		action := HTTPGetAction{
+			Path:   "/headers",
+			Method: "head",
+			HTTPHeaders: []HTTPHeader{
+				{Name: "X-Probe", Value: "yes"},
+				{Name: "Host", Value: "probe.local"},
+			},
+		}
+		success, err := runSpec(httpGetSpec(t, server, action))
+		require.True(t, success)
+		require.NoError(t, err)
+
+		action.HTTPHeaders = nil
+		success, err = runSpec(httpGetSpec(t, server, action))
+		require.False(t, success)
+		require.Equal(t, ErrBadStatusCode, err)
+	})
+	t.Run("success_codes", func(t *testing.T) {
+		action := HTTPGetAction{Path: "/teapot"}
+		success, err := runSpec(httpGetSpec(t, server, action))
+		require.False(t, success)
+		require.Equal(t, ErrBadStatusCode, err)
+
+		action.SuccessCodes = []StatusCodeRange{{From: 200, To: 299}, {From: 418, To: 418}}
+		success, err = runSpec(httpGetSpec(t, server, action))
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("redirect_policy", func(t *testing.T) {
+		action := HTTPGetAction{Path: "/redirect"}
+		success, err := runSpec(httpGetSpec(t, server, action))
+		require.False(t, success)
+		require.Equal(t, ErrBadStatusCode, err)
+
+		action.RedirectPolicy = RedirectNone
+		success, err = runSpec(httpGetSpec(t, server, action))
+		require.True(t, success)
+		require.NoError(t, err)
+
+		action.RedirectPolicy = "Sometimes"
+		_, err = httpGetSpec(t, server, action).check(&mockContainer{})
+		require.EqualError(t, err, `unknown redirect policy "Sometimes"`)
+	})
+	t.Run("expected_body", func(t *testing.T) {
+		action := HTTPGetAction{Path: "/", ExpectedBody: `"status": "ok"`}
+		success, err := runSpec(httpGetSpec(t, server, action))
+		require.True(t, success)
+		require.NoError(t, err)
+
+		action.ExpectedBody = `"status": "degraded"`
+		success, err = runSpec(httpGetSpec(t, server, action))
+		require.False(t, success)
+		require.Error(t, err)
+	})
+	t.Run("expected_body_regexp", func(t *testing.T) {
+		action := HTTPGetAction{Path: "/", ExpectedBodyRegexp: `"version": "1\.\d+\.\d+"`}
+		success, err := runSpec(httpGetSpec(t, server, action))
+		require.True(t, success)
+		require.NoError(t, err)
+
+		action.ExpectedBodyRegexp = `"version": "2\.`
+		success, err = runSpec(httpGetSpec(t, server, action))
+		require.False(t, success)
+		require.Error(t, err)
+
+		action.ExpectedBodyRegexp = `(`
+		_, err = httpGetSpec(t, server, action).check(&mockContainer{})
+		require.Error(t, err)
+	})
+	t.Run("probe_timeout", func(t *testing.T) {
+		spec := httpGetSpec(t, server, HTTPGetAction{Path: "/"})
+		spec.TimeoutSeconds = 3
+		check := spec.GetCheck(&mockContainer{})
+		require.Equal(t, 3*time.Second, check.(HTTPCheck).Client.(*http.Client).Timeout)
+	})
+}
+
+func TestHTTPGetActionTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "http-get-tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	serverCert, serverKey := writeKeyPair(t, dir, "server")
+	clientCert, clientKey := writeKeyPair(t, dir, "client")
+
+	cert, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequestClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	t.Run("unknown_authority", func(t *testing.T) {
+		action := HTTPGetAction{Scheme: "HTTPS", TLS: &HTTPTLSConfig{}}
+		success, err := runSpec(httpGetSpec(t, server, action))
+		require.False(t, success)
+		require.Error(t, err)
+	})
+	t.Run("insecure_skip_verify", func(t *testing.T) {
+		action := HTTPGetAction{
+			Scheme: "HTTPS",
+			TLS:    &HTTPTLSConfig{InsecureSkipVerify: true, CertFile: clientCert, KeyFile: clientKey},
+		}
+		success, err := runSpec(httpGetSpec(t, server, action))
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("ca_file", func(t *testing.T) {
+		action := HTTPGetAction{
+			Scheme: "HTTPS",
+			TLS:    &HTTPTLSConfig{CAFile: serverCert, CertFile: clientCert, KeyFile: clientKey},
+		}
+		success, err := runSpec(httpGetSpec(t, server, action))
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+	t.Run("no_client_cert", func(t *testing.T) {
+		action := HTTPGetAction{Scheme: "HTTPS", TLS: &HTTPTLSConfig{CAFile: serverCert}}
+		success, err := runSpec(httpGetSpec(t, server, action))
+		require.False(t, success)
+		require.Equal(t, ErrBadStatusCode, err)
+	})
+	t.Run("missing_ca_file", func(t *testing.T) {
+		action := HTTPGetAction{Scheme: "HTTPS", TLS: &HTTPTLSConfig{CAFile: filepath.Join(dir, "missing.crt")}}
+		_, err := httpGetSpec(t, server, action).check(&mockContainer{})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type InitContainerState int
+
+const (
+	InitPending   InitContainerState = iota
+	InitRunning                      // When the init container has been started
+	InitSucceeded                    // When the init container exited with a 0 status code
+	InitFailed                       // When the last run of the init container failed
+)
+
+func (state InitContainerState) String() string {
+	switch state {
+	case InitPending:
+		return "PENDING"
+	case InitRunning:
+		return "RUNNING"
+	case InitSucceeded:
+		return "SUCCEEDED"
+	case InitFailed:
+		return "FAILED"
+	}
+	return "UNKNOWN"
+}
+
+// InitContainerStatus keeps track of the progress of an init container. The timestamps
+// are those of its last run, and Restarts counts the runs that were retries.
+type InitContainerStatus struct {
+	sync.Mutex
+
+	Name         string
+	State        InitContainerState
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	LatestErrors []*ProbeError
+	Restarts     int
+}
+
+func NewInitContainerStatus(name string) *InitContainerStatus {
+	return &InitContainerStatus{
+		Name:  name,
+		State: InitPending,
+	}
+}
+
+// LastState returns the current state of the init container.
+func (status *InitContainerStatus) LastState() InitContainerState {
+	status.Lock()
+	defer status.Unlock()
+	return status.State
+}
+
+// LatestError returns the latest error of the init container, or the empty string
+// if there are none.
+func (status *InitContainerStatus) LatestError() *ProbeError {
+	status.Lock()
+	defer status.Unlock()
+	if len(status.LatestErrors) == 0 {
+		return &ProbeError{Message: ""}
+	}
+	return status.LatestErrors[len(status.LatestErrors)-1]
+}
+
+// Duration returns how long the last run of the init container took, or has been
+// taking so far if it is still running.
+func (status *InitContainerStatus) Duration(now time.Time) time.Duration {
+	status.Lock()
+	defer status.Unlock()
+	switch status.State {
+	case InitPending:
+		return 0
+	case InitRunning:
+		return now.Sub(status.StartedAt)
+	}
+	return status.FinishedAt.Sub(status.StartedAt)
+}
+
+// RecordRun marks the init container as running from now on.
+func (status *InitContainerStatus) RecordRun(now time.Time, retry bool) {
+	status.Lock()
+	defer status.Unlock()
+	if retry {
+		status.Restarts++
+	}
+	status.State = InitRunning
+	status.StartedAt = now
+	status.FinishedAt = time.Time{}
+}
+
+// RecordExit marks the init container as succeeded, or failed if err is not nil.
+func (status *InitContainerStatus) RecordExit(now time.Time, err error) {
+	status.Lock()
+	defer status.Unlock()
+	status.FinishedAt = now
+	status.State = InitSucceeded
+	if err != nil {
+		status.State = InitFailed
+		status.LatestErrors = append(status.LatestErrors, &ProbeError{
+			Message:   err.Error(),
+			Timestamp: now,
+		})
+	}
+}
+
+type InitContainerInfo struct {
+	spec   InitContainerSpec
+	ctn    Container
+	status *InitContainerStatus
+}
+
+// runInit runs the init container to completion. A failed run is retried with a fresh
+// container after the backoff delay, until the retries of the container are exhausted.
+func (c *controller) runInit(name string) (err error) {
+	var backoff time.Duration
+	info := c.getInitInfo(name)
+	for attempt := 0; attempt <= info.spec.Retries; attempt++ {
+		if attempt > 0 {
+			backoff = info.spec.Backoff.Next(backoff, 0)
+			select {
+			case <-c.stop:
+				return fmt.Errorf("controller stopped before init container %s succeeded", name)
+			case <-c.exceeded:
+				return fmt.Errorf("pod exceeded its deadline before init container %s succeeded", name)
+			case <-c.interrupted:
+				return fmt.Errorf("pod was terminated before init container %s succeeded", name)
+			case <-c.Clock.After(backoff):
+			}
+			if info, err = c.rematerializeInit(name); err != nil {
+				info.status.RecordExit(c.Clock.Now(), err)
+				return err
+			}
+		}
+		info.status.RecordRun(c.Clock.Now(), attempt > 0)
+		err = c.runInitOnce(info)
+		info.status.RecordExit(c.Clock.Now(), err)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// runInitOnce starts the init container and waits for it to exit. If it is still running
+// after its active deadline, or once the pod gets terminated, it gets killed.
+func (c *controller) runInitOnce(info InitContainerInfo) error {
+	if err := info.ctn.Start(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- info.ctn.Wait() }()
+
+	var deadline <-chan time.Time
+	if info.spec.ActiveDeadlineSeconds > 0 {
+		deadline = c.Clock.After(time.Duration(info.spec.ActiveDeadlineSeconds) * time.Second)
+	}
+	reason := ""
+	select {
+	case err := <-exited:
+		return errors.WithStack(err)
+	case <-deadline:
+		reason = fmt.Sprintf("exceeded its deadline of %d seconds", info.spec.ActiveDeadlineSeconds)
+	case <-c.stop:
+		reason = "was stopped along with the controller"
+	case <-c.exceeded:
+		reason = "was stopped after the pod exceeded its deadline"
+	case <-c.interrupted:
+		reason = "was stopped along with the pod"
+	}
+
+	if err := info.ctn.Kill(int(syscall.SIGKILL)); err != nil {
+		return errors.Wrapf(err, "failed to kill init container %s that %s", info.spec.Name, reason)
+	}
+	<-exited
+	return fmt.Errorf("init container %s %s", info.spec.Name, reason)
+}
+
+// rematerializeInit builds a new container for the init container with the given name,
+// and swaps it into InitInfos. If the controller was not given a bootstrapper the current
+// container is reused.
+func (c *controller) rematerializeInit(name string) (InitContainerInfo, error) {
+	info := c.getInitInfo(name)
+	if c.bootstrapper == nil {
+		return info, nil
+	}
+	ctn, err := c.bootstrapper(info.spec.Spec, info.spec.Metadata)
+	if err != nil {
+		return info, errors.WithStack(err)
+	}
+	info.ctn = ctn
+
+	c.Lock()
+	defer c.Unlock()
+	c.InitInfos[name] = info
+	return info, nil
+}
+
+func (c *controller) getInitInfo(name string) InitContainerInfo {
+	c.Lock()
+	defer c.Unlock()
+	return c.InitInfos[name]
+}
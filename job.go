@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// PodMode tells the controller how the pod is meant to end. A service is not expected
+// to complete, while a job completes as soon as all of its main containers finished or
+// one of them failed for good, at which point the rest of the pod gets terminated.
+type PodMode string
+
+const (
+	PodModeService PodMode = "service"
+	PodModeJob     PodMode = "job"
+)
+
+func (mode PodMode) validate() error {
+	switch mode {
+	case "", PodModeService, PodModeJob:
+		return nil
+	}
+	return fmt.Errorf("unknown pod mode %q", mode)
+}
+
+// PodResult is the outcome of a pod that completed. Its phase is either PodSucceeded or
+// PodFailed, and its exit code is the one of the first main container that failed.
+type PodResult struct {
+	Phase    PodPhase
+	Reason   string
+	ExitCode int
+}
+
+// Wait blocks until the pod completes and returns its result. It returns an error if
+// the context expires or the controller is stopped first.
+func (c *controller) Wait(ctx context.Context) (PodResult, error) {
+	select {
+	case <-c.completed:
+		c.Lock()
+		defer c.Unlock()
+		return c.result, nil
+	case <-c.stop:
+		return PodResult{}, fmt.Errorf("controller stopped before the pod completed")
+	case <-ctx.Done():
+		return PodResult{}, errors.WithStack(ctx.Err())
+	}
+}
+
+// complete records the result of the pod and unblocks Wait, only the first result
+// counts.
+func (c *controller) complete(result PodResult) {
+	c.Lock()
+	defer c.Unlock()
+	select {
+	case <-c.completed:
+		return
+	default:
+	}
+	c.result = result
+	close(c.completed)
+}
+
+// completion returns the result of the pod if it completed. A job completes when all of
+// its main containers finished, or as soon as one of them failed for good. Any other pod
+// completes once all of its main containers exited for good.
+func (c *controller) completion() (PodResult, bool) {
+	if c.mode != PodModeJob {
+		switch c.containersPhase() {
+		case PodSucceeded:
+			return PodResult{Phase: PodSucceeded, Reason: "all containers exited"}, true
+		case PodFailed:
+			return c.failure(), true
+		}
+		return PodResult{}, false
+	}
+
+	done := true
+	for _, name := range c.mainContainers() {
+		status := c.getInfo(name).status
+		switch status.LastState() {
+		case Finished:
+		case Terminal:
+			return c.failure(), true
+		case Failed:
+			if !status.RestartPending() {
+				return c.failure(), true
+			}
+			done = false
+		default:
+			done = false
+		}
+	}
+	if !done {
+		return PodResult{}, false
+	}
+	return PodResult{Phase: PodSucceeded, Reason: "all containers finished"}, true
+}
+
+// failure returns the result of a failed pod, from the first main container that
+// failed for good.
+func (c *controller) failure() PodResult {
+	for _, name := range c.mainContainers() {
+		status := c.getInfo(name).status
+		switch status.LastState() {
+		case Terminal:
+			return PodResult{
+				Phase:    PodFailed,
+				Reason:   fmt.Sprintf("container %s failed its probes and was not restarted", name),
+				ExitCode: failureCode(status),
+			}
+		case Failed:
+			if status.RestartPending() {
+				continue
+			}
+			return PodResult{
+				Phase:    PodFailed,
+				Reason:   fmt.Sprintf("container %s failed with exit code %d", name, failureCode(status)),
+				ExitCode: failureCode(status),
+			}
+		}
+	}
+	return PodResult{Phase: PodFailed, Reason: "pod failed", ExitCode: 1}
+}
+
+// finishJob terminates what is left of the pod once the job completed, and records the
+// final states of the containers.
+func (c *controller) finishJob() {
+	if !c.isTerminating() {
+		ctx, cancel := c.stopContext()
+		defer cancel()
+		c.Terminate(ctx)
+	}
+	for _, name := range c.MainOrder {
+		if c.isLaunched(name) {
+			c.update(name)
+		}
+	}
+}
+
+// failureCode returns the exit code of a container that failed for good, which is never
+// 0 even if the container was killed before it could report an error.
+func failureCode(status *ContainerStatus) int {
+	if code := status.LastExitCode(); code != 0 {
+		return code
+	}
+	return 1
+}
+
+// exitCode extracts the exit code of a container from the error returned by its Wait
+// method. A container killed by a signal exits with 128 plus the signal number, like
+// in a shell.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := errors.Cause(err).(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			if status.Signaled() {
+				return 128 + int(status.Signal())
+			}
+			return status.ExitStatus()
+		}
+	} else if coder, ok := errors.Cause(err).(interface{ ExitCode() int }); ok {
+		return coder.ExitCode()
+	}
+	return 1
+}
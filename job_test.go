@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type exitError int
+
+func (err exitError) Error() string { return "exited" }
+func (err exitError) ExitCode() int { return int(err) }
+
+func TestExitCode(t *testing.T) {
+	require.Equal(t, 0, exitCode(nil))
+	require.Equal(t, 1, exitCode(errors.New("failed")))
+	require.Equal(t, 4, exitCode(exitError(4)))
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	require.Equal(t, 3, exitCode(err))
+	err = exec.Command("sh", "-c", "kill -9 $$").Run()
+	require.Equal(t, 137, exitCode(err))
+}
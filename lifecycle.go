@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultHookTimeout = 30 * time.Second
+
+// Lifecycle holds the hooks of a container. PostStart runs as soon as the container
+// has started, and the probes of the container only start once it succeeded; if it
+// fails the container is killed and moves to Failed. PreStop runs when the container
+// gets terminated, before it is sent its stop signal, and counts against its grace
+// period. A failed PreStop is recorded but does not prevent the termination.
+type Lifecycle struct {
+	PostStart *LifecycleHandler
+	PreStop   *LifecycleHandler
+}
+
+// LifecycleHandler runs its action against the container, giving up after TimeoutSeconds,
+// which defaults to 30 seconds.
+type LifecycleHandler struct {
+	Action         `json:",inline" yaml:",inline"`
+	TimeoutSeconds int
+}
+
+// GetCheck returns the check of the handler, bounded by its timeout. The check always fails
+// if the action of the handler is not valid.
+func (handler LifecycleHandler) GetCheck(ctn Container) Check {
+	return orFailingCheck(handler.check(ctn))
+}
+
+// check returns the check of the handler, or an error if its action is not valid.
+func (handler LifecycleHandler) check(ctn Container) (Check, error) {
+	return handler.Action.getCheck(ctn, handler.timeout())
+}
+
+// validate makes sure that the actions of the hooks are valid for the container.
+func (lifecycle Lifecycle) validate(ctn Container) error {
+	if lifecycle.PostStart != nil {
+		if _, err := lifecycle.PostStart.check(ctn); err != nil {
+			return errors.Wrapf(err, "invalid postStart hook")
+		}
+	}
+	if lifecycle.PreStop != nil {
+		if _, err := lifecycle.PreStop.check(ctn); err != nil {
+			return errors.Wrapf(err, "invalid preStop hook")
+		}
+	}
+	return nil
+}
+
+func (handler LifecycleHandler) timeout() time.Duration {
+	if handler.TimeoutSeconds <= 0 {
+		return defaultHookTimeout
+	}
+	return time.Duration(handler.TimeoutSeconds) * time.Second
+}
+
+// runHook runs the action of the handler against the container and waits for it to
+// complete, until the timeout expires or the cancel channel gets closed.
+func (c *controller) runHook(name, hook string, handler *LifecycleHandler, ctn Container,
+	timeout time.Duration, cancel <-chan struct{}) error {
+	check, err := handler.check(ctn)
+	if err != nil {
+		return errors.Wrapf(err, "%s hook of container %s is invalid", hook, name)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	type result struct {
+		success bool
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		success, err := check.Run(ctx)
+		done <- result{success, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return errors.Wrapf(res.err, "%s hook of container %s failed", hook, name)
+		} else if !res.success {
+			return fmt.Errorf("%s hook of container %s failed", hook, name)
+		}
+		return nil
+	case <-c.Clock.After(timeout):
+		return fmt.Errorf("%s hook of container %s timed out after %v", hook, name, timeout)
+	case <-cancel:
+		return fmt.Errorf("%s hook of container %s was cancelled", hook, name)
+	}
+}
+
+// postStartHook returns the function the probe set of the container runs once the
+// container has started, or nil if the container has no postStart hook.
+func (c *controller) postStartHook(spec ContainerSpec, ctn Container) func(stop <-chan struct{}) error {
+	handler := spec.Lifecycle.PostStart
+	if handler == nil {
+		return nil
+	}
+	return func(stop <-chan struct{}) error {
+		return c.runHook(spec.Name, "postStart", handler, ctn, handler.timeout(), stop)
+	}
+}
+
+// preStop runs the preStop hook of the container, if it has one, and returns how much
+// of the grace period it used up. The hook cannot run for longer than the grace period.
+func (c *controller) preStop(info ContainerInfo, grace time.Duration, cancel <-chan struct{}) time.Duration {
+	handler := info.spec.Lifecycle.PreStop
+	if handler == nil {
+		return 0
+	}
+	timeout := handler.timeout()
+	if grace < timeout {
+		timeout = grace
+	}
+
+	start := c.Clock.Now()
+	if err := c.runHook(info.spec.Name, "preStop", handler, info.ctn, timeout, cancel); err != nil {
+		info.status.AddError(&ProbeError{Message: err.Error(), Timestamp: c.Clock.Now()})
+	}
+	return c.Clock.Now().Sub(start)
+}
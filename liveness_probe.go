@@ -16,8 +16,9 @@ func NewLivenessProbe(check Check) *LongLivedProbe {
 			SuccessThreshold: 1,
 			FailureThreshold: 1,
 		},
-		Check:     check,
-		Clock:     clock.New(),
-		isHealthy: true,
+		Check:         check,
+		Clock:         clock.New(),
+		StopOnFailure: true,
+		isHealthy:     true,
 	}
 }
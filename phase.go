@@ -0,0 +1,91 @@
+package controller
+
+// PodPhase is a high level summary of where the pod is in its lifecycle, with the
+// same meaning as the phase of a Kubernetes pod.
+type PodPhase int
+
+const (
+	PodPending      PodPhase = iota // When the controller has not been started yet
+	PodInitializing                 // When the init containers are running
+	PodRunning                      // When the containers have been started and some have not exited for good
+	PodSucceeded                    // When all the containers exited with a 0 status code
+	PodFailed                       // When an init container failed, all the containers exited and one of them failed, the pod exceeded its deadline, or it got terminated before its containers were launched
+	PodUnknown                      // When the controller got stopped before the pod completed
+)
+
+func (phase PodPhase) String() string {
+	switch phase {
+	case PodPending:
+		return "PENDING"
+	case PodInitializing:
+		return "INITIALIZING"
+	case PodRunning:
+		return "RUNNING"
+	case PodSucceeded:
+		return "SUCCEEDED"
+	case PodFailed:
+		return "FAILED"
+	}
+	return "UNKNOWN"
+}
+
+// Phase computes the phase of the pod from the progress of the init sequence and the
+// states of the containers.
+func (c *controller) Phase() PodPhase {
+	c.Lock()
+	started, initialized, initErr, reason := c.started, c.initialized, c.initErr, c.reason
+	c.Unlock()
+
+	phase := PodRunning
+	switch {
+	case !started:
+		return PodPending
+	case initErr != nil, reason != "":
+		return PodFailed
+	case !initialized:
+		phase = PodInitializing
+	default:
+		phase = c.containersPhase()
+	}
+
+	if phase != PodSucceeded && phase != PodFailed && c.stopped() {
+		return PodUnknown
+	}
+	return phase
+}
+
+// containersPhase looks at the states of the main containers once the init sequence has
+// succeeded, sidecars are left out. A container that exited and is due for a restart
+// keeps the pod running.
+func (c *controller) containersPhase() PodPhase {
+	done, failed := true, false
+	for _, name := range c.mainContainers() {
+		status := c.getInfo(name).status
+		switch status.LastState() {
+		case Finished:
+		case Failed, Terminal:
+			failed = true
+		default:
+			done = false
+		}
+		if status.RestartPending() {
+			done = false
+		}
+	}
+
+	if !done {
+		return PodRunning
+	} else if failed {
+		return PodFailed
+	}
+	return PodSucceeded
+}
+
+func (c *controller) stopped() bool {
+	select {
+	case <-c.stop:
+		return true
+	default:
+		return false
+	}
+}
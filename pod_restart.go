@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// PodRestartPolicy decides what gets restarted when a container fails. By default only
+// the container itself is, according to its own RestartPolicy. With the Pod policy, the
+// failure of a main container brings down all of the main containers, the init
+// containers are run again and the main containers are relaunched. Sidecars are left
+// running.
+type PodRestartPolicy string
+
+const (
+	PodRestartContainer PodRestartPolicy = "Container"
+	PodRestartPod       PodRestartPolicy = "Pod"
+)
+
+func (policy PodRestartPolicy) validate() error {
+	switch policy {
+	case "", PodRestartContainer, PodRestartPod:
+		return nil
+	}
+	return fmt.Errorf("unknown pod restart policy %q", policy)
+}
+
+// Restarts returns how many times the whole pod was restarted.
+func (c *controller) Restarts() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.podRestarts
+}
+
+// restartsPod returns true if the failure of the container restarts the whole pod.
+func (c *controller) restartsPod(spec ContainerSpec) bool {
+	return c.podRestartPolicy == PodRestartPod && !spec.isSidecar()
+}
+
+// podRestartReason returns why the pod must be restarted, or the empty string if it
+// does not need to be.
+func (c *controller) podRestartReason() string {
+	if c.podRestartPolicy != PodRestartPod || c.isTerminating() {
+		return ""
+	}
+	for _, name := range c.mainContainers() {
+		switch state := c.getInfo(name).status.LastState(); state {
+		case Failed, Terminal:
+			return fmt.Sprintf("container %s is %v", name, state)
+		}
+	}
+	return ""
+}
+
+// restartPod stops all of the main containers, runs the init containers again through
+// the same path as Start, and gets the main containers to be launched again. It waits
+// for the backoff of the pod beforehand, and gives up on the restart if the pod gets
+// terminated in the meantime. It returns false if the pod must not be watched anymore.
+func (c *controller) restartPod(reason string) bool {
+	now := c.Clock.Now()
+	c.Lock()
+	c.podBackoff = c.backoff.Next(c.podBackoff, now.Sub(c.podStartedAt))
+	backoff := c.podBackoff
+	c.initialized = false
+	c.Unlock()
+
+	ctx, cancel := c.stopContext()
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, name := range c.mainContainers() {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			c.stopContainer(ctx, name)
+		}(name)
+	}
+	wg.Wait()
+
+	select {
+	case <-c.stop:
+		return false
+	case <-c.interrupted:
+		c.abandon()
+		return false
+	case <-c.Clock.After(backoff):
+	}
+
+	for _, name := range c.InitOrder {
+		if _, err := c.rematerializeInit(name); err != nil {
+			c.Lock()
+			c.initErr = err
+			c.Unlock()
+			return false
+		}
+	}
+	if !c.initialize() {
+		return false
+	}
+
+	c.Lock()
+	c.podRestarts++
+	c.podStartedAt = c.Clock.Now()
+	c.Unlock()
+	for _, name := range c.mainContainers() {
+		info, err := c.rematerialize(name)
+		if err != nil {
+			info.status.AddError(&ProbeError{
+				Message:   fmt.Sprintf("failed to restart container %s: %v", name, err),
+				Timestamp: c.Clock.Now(),
+			})
+			continue
+		}
+		info.status.RecordPodRestart(c.Clock.Now(), fmt.Sprintf("pod restarted: %s", reason))
+
+		c.Lock()
+		c.launched[name] = false
+		c.Unlock()
+	}
+	return true
+}
+
+// stopContainer brings down a main container ahead of a restart of the pod, giving it
+// its grace period, and stops its probes.
+func (c *controller) stopContainer(ctx context.Context, name string) {
+	info := c.getInfo(name)
+	info.status.CancelRestart()
+	if info.probes.Exit.Running() {
+		termination := &Termination{Signal: info.spec.stopSignal()}
+		if err := c.signal(ctx, info, info.spec.gracePeriod(), termination); err != nil {
+			info.status.AddError(&ProbeError{
+				Message:   fmt.Sprintf("failed to stop container %s to restart the pod: %v", name, err),
+				Timestamp: c.Clock.Now(),
+			})
+		}
+	}
+	info.probes.Stop()
+}
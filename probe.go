@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"runtime"
 	"sync"
 	"time"
@@ -15,17 +17,22 @@ import (
 // Healthy can also return an error, this will typically correspond to
 // the last error encountered by the probe, so even a `true` healthy bit
 // might return a non-nil error.
+// Done returns a channel that gets closed once the background goroutines
+// of the probe have returned.
 type Probe interface {
 	Start()
 	Healthy() (healthy bool, err error)
 	Started() bool
 	Running() bool
 	Stop()
+	Done() <-chan struct{}
 }
 
 var _ Probe = NewLivenessProbe(nil)
 var _ Probe = NewReadinessProbe(nil)
+var _ Probe = NewStartupProbe(nil)
 var _ Probe = &ExitProbe{}
+var _ Probe = NewPassingProbe()
 
 type BaseProbe struct {
 	sync.Mutex
@@ -65,6 +72,7 @@ func (p *BaseProbe) onTickResult(success bool, err error) {
 func (p *BaseProbe) onTimeout() {
 	p.Lock()
 	defer p.Unlock()
+	p.err = fmt.Errorf("probe timed out after %v", p.Timeout)
 	p.hasFailed = true
 	p.consecutiveFailures += 1
 	p.consecutiveSuccesses = 0
@@ -79,9 +87,21 @@ type LongLivedProbe struct {
 	Check Check
 	Clock clock.Clock
 
+	// StopOnSuccess makes the probe stop once it is healthy, instead of carrying on
+	// until it fails.
+	StopOnSuccess bool
+
+	// StopOnFailure makes the probe stop for good once it reaches FailureThreshold.
+	// Without it the probe keeps running, and becomes healthy again after
+	// SuccessThreshold consecutive successes.
+	StopOnFailure bool
+
 	isRunning  bool
 	isHealthy  bool
 	hasStarted bool
+
+	stop chan struct{}
+	done chan struct{}
 }
 
 func newLongLivedProbe(check Check) *LongLivedProbe {
@@ -94,8 +114,9 @@ func newLongLivedProbe(check Check) *LongLivedProbe {
 			SuccessThreshold: 1,
 			FailureThreshold: 1,
 		},
-		Check: check,
-		Clock: clock.New(),
+		Check:         check,
+		Clock:         clock.New(),
+		StopOnFailure: true,
 	}
 }
 
@@ -103,11 +124,16 @@ func (p *LongLivedProbe) Start() {
 	p.Lock()
 	p.isRunning = true
 	p.hasStarted = true
+	p.done = make(chan struct{})
+	stop, exited := p.stopChan(), p.done
 	p.Unlock()
 
 	go func() {
-		p.Clock.Sleep(p.InitialDelay)
-		done := make(chan bool)
+		defer close(exited)
+		if !p.sleep(p.InitialDelay, stop) {
+			return
+		}
+		var inflight <-chan tickResult
 		for {
 			// If stop was called by another goroutine then we return from the
 			// run loop.
@@ -115,47 +141,98 @@ func (p *LongLivedProbe) Start() {
 				return
 			}
 
-			// We know the probe is running and hasnt failed out yet, so we run
-			// a single tick.
+			// A check that timed out got cancelled, but the next one only starts once
+			// it returned so that the checks of the probe never overlap. Every period
+			// spent waiting on it counts as another timeout.
+			if inflight != nil {
+				select {
+				case <-inflight:
+					inflight = nil
+				default:
+				}
+			}
+
 			var success bool
-			var err error
-			go func() {
-				success, err = p.Check.Run()
-				done <- true
-			}()
-			runtime.Gosched()
-
-			// Check for timeout of that tick here, and process the result of
-			// the tick.
-			select {
-			case <-p.Clock.After(p.Timeout):
+			if inflight != nil {
 				p.onTimeout()
-			case <-done:
-				p.onTickResult(success, err)
+			} else {
+				// We know the probe is running and hasnt failed out yet, so we run
+				// a single tick. The channel is buffered so that a tick that timed
+				// out can still return.
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan tickResult, 1)
+				go func() {
+					success, err := p.Check.Run(ctx)
+					done <- tickResult{success, err}
+				}()
+				runtime.Gosched()
+
+				// Check for timeout of that tick here, and process the result of
+				// the tick. The check gets cancelled whenever we stop waiting for it.
+				select {
+				case <-p.Clock.After(p.Timeout):
+					cancel()
+					p.onTimeout()
+					inflight = done
+				case res := <-done:
+					cancel()
+					success = res.success
+					p.onTickResult(res.success, res.err)
+				case <-stop:
+					cancel()
+					return
+				}
 			}
 
 			// Check for max successes and failures. If the max failures in a row
-			// has been reached we stop the probe and set its state to UNHEALTHY.
+			// has been reached we set its state to UNHEALTHY, and stop the probe if
+			// it stops on failure.
 			p.Lock()
 			if p.consecutiveFailures >= p.FailureThreshold {
 				p.isHealthy = false
-				p.Unlock()
-				p.Stop()
-				return
+				if p.StopOnFailure {
+					p.Unlock()
+					p.Stop()
+					return
+				}
 			} else if p.consecutiveSuccesses >= p.SuccessThreshold ||
 				(!p.hasFailed && success) || (!p.hasSucceeded && success) {
 				p.isHealthy = true
 			} else if !success {
 				p.isHealthy = false
 			}
+			if p.StopOnSuccess && p.isHealthy {
+				p.Unlock()
+				p.Stop()
+				return
+			}
 			p.Unlock()
 
 			// This line is hit if we have not hit either of the thresholds.
-			p.Clock.Sleep(p.Period)
+			if !p.sleep(p.Period, stop) {
+				return
+			}
 		}
 	}()
 }
 
+// tickResult is the outcome of a single run of the check of a probe.
+type tickResult struct {
+	success bool
+	err     error
+}
+
+// sleep waits for the given duration on the clock of the probe, and returns false
+// if the probe was stopped in the meantime.
+func (p *LongLivedProbe) sleep(d time.Duration, stop <-chan struct{}) bool {
+	select {
+	case <-p.Clock.After(d):
+		return true
+	case <-stop:
+		return false
+	}
+}
+
 func (p *LongLivedProbe) Healthy() (bool, error) {
 	p.Lock()
 	defer p.Unlock()
@@ -177,5 +254,69 @@ func (p *LongLivedProbe) Started() bool {
 func (p *LongLivedProbe) Stop() {
 	p.Lock()
 	defer p.Unlock()
+	if p.isRunning {
+		close(p.stopChan())
+	}
 	p.isRunning = false
 }
+
+func (p *LongLivedProbe) Done() <-chan struct{} {
+	p.Lock()
+	defer p.Unlock()
+	if p.done == nil {
+		return closedChan()
+	}
+	return p.done
+}
+
+// stopChan returns the channel closed when the probe gets stopped, it must be called
+// with the lock held.
+func (p *LongLivedProbe) stopChan() chan struct{} {
+	if p.stop == nil {
+		p.stop = make(chan struct{})
+	}
+	return p.stop
+}
+
+// A PassingProbe stands in for a probe that has no action. It is healthy for as long as
+// it runs, so the container is never killed or kept from being ready because of it.
+type PassingProbe struct {
+	sync.Mutex
+
+	isRunning  bool
+	hasStarted bool
+}
+
+func NewPassingProbe() *PassingProbe {
+	return &PassingProbe{}
+}
+
+func (p *PassingProbe) Start() {
+	p.Lock()
+	defer p.Unlock()
+	p.isRunning = true
+	p.hasStarted = true
+}
+
+func (p *PassingProbe) Healthy() (bool, error) { return true, nil }
+
+func (p *PassingProbe) Started() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.hasStarted
+}
+
+func (p *PassingProbe) Running() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.isRunning
+}
+
+func (p *PassingProbe) Stop() {
+	p.Lock()
+	defer p.Unlock()
+	p.isRunning = false
+}
+
+// Done returns a closed channel, the probe has no background goroutine.
+func (p *PassingProbe) Done() <-chan struct{} { return closedChan() }
@@ -1,15 +1,36 @@
 package controller
 
 import (
+	"sync"
 	"time"
 )
 
 type ProbeSet struct {
+	sync.Mutex
+
 	Exit      *ExitProbe
 	Liveness  Probe
 	Readiness Probe
 
+	// Startup is optional, the liveness and readiness probes only start once it
+	// has succeeded.
+	Startup Probe
+
+	// Sleeper paces the checks of the exit probe while the container starts. After
+	// takes precedence over it when it is set, and its wait gets cut short when the
+	// probe set is stopped.
 	Sleeper func(time.Duration)
+	After   func(time.Duration) <-chan time.Time
+
+	// PostStart is optional, it runs once the container has started and before the
+	// liveness and readiness probes. If it fails the container gets killed.
+	PostStart func(stop <-chan struct{}) error
+
+	postStartErr error
+	killed       bool
+	stopped      bool
+	stop         chan struct{}
+	launched     chan struct{}
 }
 
 func NewProbeSet(exit *ExitProbe, liveness, readiness Probe) *ProbeSet {
@@ -18,16 +39,168 @@ func NewProbeSet(exit *ExitProbe, liveness, readiness Probe) *ProbeSet {
 		Liveness:  liveness,
 		Readiness: readiness,
 		Sleeper:   time.Sleep,
+		stop:      make(chan struct{}),
 	}
 }
 
 func (pset *ProbeSet) Start() {
+	pset.Lock()
+	pset.launched = make(chan struct{})
+	launched := pset.launched
+	pset.Unlock()
+
 	pset.Exit.Start()
 	go func() {
+		defer close(launched)
 		for !pset.Exit.Waiting() {
-			pset.Sleeper(1 * time.Second)
+			// The container failed to start, there is nothing to probe.
+			if !pset.Exit.Running() {
+				return
+			}
+			select {
+			case <-pset.stop:
+				return
+			case <-pset.after(1 * time.Second):
+			}
+		}
+		var err error
+		if pset.PostStart != nil {
+			err = pset.PostStart(pset.stop)
+		}
+		pset.Lock()
+		if pset.stopped {
+			pset.Unlock()
+			return
+		} else if err != nil {
+			pset.postStartErr = err
+			pset.Exit.Stop()
+			pset.Unlock()
+			return
+		} else if pset.Startup != nil {
+			pset.Startup.Start()
+		}
+		startup := pset.startupDone()
+		pset.Unlock()
+
+		select {
+		case <-pset.stop:
+			return
+		case <-startup:
+		}
+		pset.Lock()
+		defer pset.Unlock()
+		if pset.stopped || !pset.startupSucceeded() {
+			return
 		}
 		pset.Liveness.Start()
 		pset.Readiness.Start()
 	}()
 }
+
+// after returns a channel that receives once the duration has elapsed, from After if it
+// is set and through Sleeper otherwise.
+func (pset *ProbeSet) after(d time.Duration) <-chan time.Time {
+	if pset.After != nil {
+		return pset.After(d)
+	}
+	elapsed := make(chan time.Time, 1)
+	go func() {
+		pset.Sleeper(d)
+		elapsed <- time.Now()
+	}()
+	return elapsed
+}
+
+// PostStartError returns the error of the postStart hook if it failed.
+func (pset *ProbeSet) PostStartError() error {
+	pset.Lock()
+	defer pset.Unlock()
+	return pset.postStartErr
+}
+
+// StartupFailed returns true if the startup probe gave up before succeeding.
+func (pset *ProbeSet) StartupFailed() bool {
+	pset.Lock()
+	defer pset.Unlock()
+	if pset.Startup == nil || pset.stopped || !pset.Startup.Started() || pset.Startup.Running() {
+		return false
+	}
+	return !pset.startupSucceeded()
+}
+
+// LivenessFailed returns true if the liveness probe gave up on the container.
+func (pset *ProbeSet) LivenessFailed() bool {
+	pset.Lock()
+	defer pset.Unlock()
+	return !pset.stopped && pset.Liveness.Started() && !pset.Liveness.Running()
+}
+
+// MarkKilled returns true the first time it is called, so that the container of the
+// probe set only gets killed once.
+func (pset *ProbeSet) MarkKilled() bool {
+	pset.Lock()
+	defer pset.Unlock()
+	killed := pset.killed
+	pset.killed = true
+	return !killed
+}
+
+// startupDone returns a channel closed once the startup probe has returned, it must be
+// called with the lock held.
+func (pset *ProbeSet) startupDone() <-chan struct{} {
+	if pset.Startup == nil {
+		return closedChan()
+	}
+	return pset.Startup.Done()
+}
+
+// startupSucceeded must be called with the lock held.
+func (pset *ProbeSet) startupSucceeded() bool {
+	if pset.Startup == nil {
+		return true
+	}
+	healthy, _ := pset.Startup.Healthy()
+	return healthy
+}
+
+// Stop stops all of the probes of the set. Stopping the exit probe kills the
+// container if it is still running.
+func (pset *ProbeSet) Stop() {
+	pset.Lock()
+	defer pset.Unlock()
+	if !pset.stopped {
+		close(pset.stop)
+	}
+	pset.stopped = true
+	if pset.Startup != nil {
+		pset.Startup.Stop()
+	}
+	pset.Liveness.Stop()
+	pset.Readiness.Stop()
+	pset.Exit.Stop()
+}
+
+// Done returns a channel that gets closed once every goroutine started by the
+// probe set has returned.
+func (pset *ProbeSet) Done() <-chan struct{} {
+	pset.Lock()
+	launched := pset.launched
+	pset.Unlock()
+	if launched == nil {
+		return closedChan()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		<-launched
+		probes := []Probe{pset.Exit, pset.Liveness, pset.Readiness}
+		if pset.Startup != nil {
+			probes = append(probes, pset.Startup)
+		}
+		for _, probe := range probes {
+			<-probe.Done()
+		}
+		close(done)
+	}()
+	return done
+}
@@ -1,26 +1,50 @@
 package controller
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 )
 
+// Action is what gets run against a container, either by a probe or by a lifecycle
+// hook. Only one of its fields is expected to be set.
+type Action struct {
+	Exec      *[]string
+	HTTPGet   *HTTPGetAction
+	TCPSocket *TCPSocketAction
+	GRPC      *GRPCAction
+}
+
+// TCPSocketAction succeeds if a TCP connection can be opened to the port.
+type TCPSocketAction struct {
+	Host string
+	Port int
+}
+
+// GRPCAction calls the gRPC health checking protocol on the port, for the service if it is
+// set, and succeeds if it is SERVING. With InsecureTLS the call goes over TLS without
+// verifying the certificate of the server.
+type GRPCAction struct {
+	Port        int
+	Service     string
+	InsecureTLS bool
+}
+
 type ProbeSpec struct {
-	Exec    *[]string
-	HTTPGet *struct {
-		Host   string
-		Path   string
-		Port   int
-		Scheme string
-		// TODO: add headers
-	}
-	// TODO: add TCP socket
+	Action `json:",inline" yaml:",inline"`
 
 	InitialDelaySeconds int
 	PeriodSeconds       int
 	SuccessThreshold    int
 	FailureThreshold    int
 	TimeoutSeconds      int
+
+	// GracefulKill only applies to liveness and startup probes. When set, a container
+	// that fails the probe gets its stop signal and its termination grace period before
+	// being sent SIGKILL, otherwise it is sent SIGKILL right away.
+	GracefulKill bool
 }
 
 func NewProbeSpec() ProbeSpec {
@@ -32,26 +56,124 @@ func NewProbeSpec() ProbeSpec {
 	}
 }
 
+// defaultProbeTimeout is the timeout of the probes that do not set one.
+const defaultProbeTimeout = 1 * time.Second
+
+// apply sets the timings and thresholds of the spec on the probe. The initial delay always
+// comes from the spec, while the fields left at 0 keep the defaults of the probe.
+func (p ProbeSpec) apply(base *BaseProbe) {
+	base.InitialDelay = time.Duration(p.InitialDelaySeconds) * time.Second
+	if p.PeriodSeconds > 0 {
+		base.Period = time.Duration(p.PeriodSeconds) * time.Second
+	}
+	if p.TimeoutSeconds > 0 {
+		base.Timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	if p.SuccessThreshold > 0 {
+		base.SuccessThreshold = p.SuccessThreshold
+	}
+	if p.FailureThreshold > 0 {
+		base.FailureThreshold = p.FailureThreshold
+	}
+}
+
+// GetBaseProbe returns the timings and thresholds of the spec, the fields it leaves at 0
+// keep the defaults of the long-lived probes.
 func (p ProbeSpec) GetBaseProbe() BaseProbe {
+	base := &newLongLivedProbe(nil).BaseProbe
+	p.apply(base)
 	return BaseProbe{
-		InitialDelay:     time.Duration(p.InitialDelaySeconds) * time.Second,
-		Period:           time.Duration(p.PeriodSeconds) * time.Second,
-		Timeout:          time.Duration(p.TimeoutSeconds) * time.Second,
-		SuccessThreshold: p.SuccessThreshold,
-		FailureThreshold: p.FailureThreshold,
+		InitialDelay:     base.InitialDelay,
+		Period:           base.Period,
+		Timeout:          base.Timeout,
+		SuccessThreshold: base.SuccessThreshold,
+		FailureThreshold: base.FailureThreshold,
+	}
+}
+
+func (p ProbeSpec) timeout() time.Duration {
+	if p.TimeoutSeconds <= 0 {
+		return defaultProbeTimeout
 	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
 }
 
+// GetCheck returns the check of the probe, bounded by the timeout of the probe. The check
+// always fails if the action of the probe is not valid.
 func (p ProbeSpec) GetCheck(ctn Container) Check {
-	if p.HTTPGet != nil {
-		host := fmt.Sprintf("%s:%v", p.HTTPGet.Host, p.HTTPGet.Port)
-		httpcheck := NewHTTPCheck(host, p.HTTPGet.Path)
-		httpcheck.Scheme = p.HTTPGet.Scheme
-		return httpcheck
-	} else if p.Exec != nil {
+	return orFailingCheck(p.check(ctn))
+}
+
+// check returns the check of the probe, or an error if its action is not valid.
+func (p ProbeSpec) check(ctn Container) (Check, error) {
+	return p.Action.getCheck(ctn, p.timeout())
+}
+
+// hasAction returns true if one of the fields of the action is set.
+func (a Action) hasAction() bool {
+	return a.Exec != nil || a.HTTPGet != nil || a.TCPSocket != nil || a.GRPC != nil
+}
+
+// GetCheck returns the check that runs the action against the container. The check always
+// fails if the action is not valid.
+func (a Action) GetCheck(ctn Container) Check {
+	return orFailingCheck(a.getCheck(ctn, 0))
+}
+
+// getCheck returns the check of the action, the checks that can bound themselves give up
+// after the timeout if it is not 0. It fails if the action is not valid.
+func (a Action) getCheck(ctn Container, timeout time.Duration) (Check, error) {
+	// Without a host, the TCP and HTTP checks target the container itself if it has its
+	// own network address, as do the gRPC checks which never have one.
+	addresser, _ := ctn.(Addresser)
+	if a.TCPSocket != nil {
+		address := net.JoinHostPort(a.TCPSocket.Host, strconv.Itoa(a.TCPSocket.Port))
+		check := NewTCPCheck(address, timeout)
+		if a.TCPSocket.Host == "" && addresser != nil {
+			check.Dial = addresser.Dialer()
+			return addressedCheck(addresser, a.TCPSocket.Port, func(address string) Check {
+				addressed := check
+				addressed.Address = address
+				return addressed
+			}), nil
+		}
+		return check, nil
+	} else if a.GRPC != nil {
+		address := net.JoinHostPort("localhost", strconv.Itoa(a.GRPC.Port))
+		check := NewGRPCCheck(address, a.GRPC.Service, timeout)
+		check.InsecureTLS = a.GRPC.InsecureTLS
+		if addresser != nil {
+			check.Dial = addresser.Dialer()
+			return addressedCheck(addresser, a.GRPC.Port, func(address string) Check {
+				addressed := check
+				addressed.Address = address
+				return addressed
+			}), nil
+		}
+		return check, nil
+	} else if a.HTTPGet != nil {
+		var dial dialFunc
+		if a.HTTPGet.Host == "" && addresser != nil {
+			dial = addresser.Dialer()
+		}
+		check, err := a.HTTPGet.check(timeout, dial)
+		if err != nil {
+			return nil, err
+		} else if a.HTTPGet.Host == "" && addresser != nil {
+			return addressedCheck(addresser, a.HTTPGet.Port, func(address string) Check {
+				addressed := check
+				addressed.Host = address
+				return addressed
+			}), nil
+		}
+		return check, nil
+	} else if a.Exec != nil {
+		if len(*a.Exec) == 0 {
+			return nil, fmt.Errorf("exec action has no command")
+		}
 		return RunnerCheck{
-			Runner: func() error {
-				code, err := ctn.Exec((*p.Exec)[0], (*p.Exec)[1:]...)
+			Runner: func(ctx context.Context) error {
+				code, err := execContext(ctx, ctn, (*a.Exec)[0], (*a.Exec)[1:]...)
 				if err != nil {
 					return err
 				} else if code != 0 {
@@ -59,11 +181,11 @@ func (p ProbeSpec) GetCheck(ctn Container) Check {
 				}
 				return nil
 			},
-		}
+		}, nil
 	}
 
 	// By default a check will constantly return healthy.
-	return HealthyCheck{}
+	return HealthyCheck{}, nil
 }
 
 func (p ProbeSpec) setExec(program string, arguments ...string) ProbeSpec {
@@ -72,20 +194,60 @@ func (p ProbeSpec) setExec(program string, arguments ...string) ProbeSpec {
 	return p
 }
 
-type LivenessProbeSpec struct{ ProbeSpec }
+type LivenessProbeSpec struct {
+	ProbeSpec `json:",inline" yaml:",inline"`
+}
+
+type ReadinessProbeSpec struct {
+	ProbeSpec `json:",inline" yaml:",inline"`
+}
 
-type ReadinessProbeSpec struct{ ProbeSpec }
+type StartupProbeSpec struct {
+	ProbeSpec `json:",inline" yaml:",inline"`
+}
 
+// Materialize returns the liveness probe of the container, or a PassingProbe if the spec
+// has no action.
 func (p LivenessProbeSpec) Materialize(ctn Container) (Probe, error) {
-	check := p.GetCheck(ctn)
+	if !p.hasAction() {
+		return NewPassingProbe(), nil
+	}
+	check, err := p.check(ctn)
+	if err != nil {
+		return nil, err
+	}
 	probe := NewLivenessProbe(check)
-	probe.BaseProbe = p.GetBaseProbe()
+	p.apply(&probe.BaseProbe)
 	return probe, nil
 }
 
+// Materialize returns the readiness probe of the container, or a PassingProbe if the spec
+// has no action.
 func (p ReadinessProbeSpec) Materialize(ctn Container) (Probe, error) {
-	check := p.GetCheck(ctn)
+	if !p.hasAction() {
+		return NewPassingProbe(), nil
+	}
+	check, err := p.check(ctn)
+	if err != nil {
+		return nil, err
+	}
 	probe := NewReadinessProbe(check)
-	probe.BaseProbe = p.GetBaseProbe()
+	p.apply(&probe.BaseProbe)
+	return probe, nil
+}
+
+// Materialize returns the startup probe of the container, keeping the defaults of
+// NewStartupProbe for the fields the spec leaves unset, or a PassingProbe if the spec has
+// no action.
+func (p StartupProbeSpec) Materialize(ctn Container) (Probe, error) {
+	if !p.hasAction() {
+		return NewPassingProbe(), nil
+	}
+	check, err := p.check(ctn)
+	if err != nil {
+		return nil, err
+	}
+	probe := NewStartupProbe(check)
+	p.apply(&probe.BaseProbe)
 	return probe, nil
 }
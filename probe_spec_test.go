@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestProbeSpecDecoding(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		contents := `
+name: main
+livenessprobe:
+  exec: ["cat", "/tmp/health"]
+  periodseconds: 2
+readinessprobe:
+  httpget:
+    path: /ready
+    port: 8080
+startupprobe:
+  tcpsocket:
+    port: 8081
+lifecycle:
+  poststart:
+    grpc:
+      port: 8082
+  prestop:
+    exec: ["drain"]
+    timeoutseconds: 10
+`
+		var spec ContainerSpec
+		require.NoError(t, yaml.Unmarshal([]byte(contents), &spec))
+
+		require.Equal(t, &[]string{"cat", "/tmp/health"}, spec.LivenessProbe.Exec)
+		require.Equal(t, 2, spec.LivenessProbe.PeriodSeconds)
+		require.NotNil(t, spec.ReadinessProbe.HTTPGet)
+		require.Equal(t, "/ready", spec.ReadinessProbe.HTTPGet.Path)
+		require.Equal(t, 8080, spec.ReadinessProbe.HTTPGet.Port)
+		require.NotNil(t, spec.StartupProbe)
+		require.Equal(t, &TCPSocketAction{Port: 8081}, spec.StartupProbe.TCPSocket)
+		require.NotNil(t, spec.Lifecycle.PostStart)
+		require.Equal(t, &GRPCAction{Port: 8082}, spec.Lifecycle.PostStart.GRPC)
+		require.NotNil(t, spec.Lifecycle.PreStop)
+		require.Equal(t, &[]string{"drain"}, spec.Lifecycle.PreStop.Exec)
+		require.Equal(t, 10, spec.Lifecycle.PreStop.TimeoutSeconds)
+	})
+	t.Run("json", func(t *testing.T) {
+		contents := `{
+			"name": "main",
+			"livenessProbe": {"exec": ["cat", "/tmp/health"], "periodSeconds": 2},
+			"lifecycle": {"preStop": {"httpGet": {"path": "/drain", "port": 8080}}}
+		}`
+		var spec ContainerSpec
+		require.NoError(t, json.Unmarshal([]byte(contents), &spec))
+
+		require.Equal(t, &[]string{"cat", "/tmp/health"}, spec.LivenessProbe.Exec)
+		require.Equal(t, 2, spec.LivenessProbe.PeriodSeconds)
+		require.NotNil(t, spec.Lifecycle.PreStop)
+		require.NotNil(t, spec.Lifecycle.PreStop.HTTPGet)
+		require.Equal(t, "/drain", spec.Lifecycle.PreStop.HTTPGet.Path)
+	})
+}
+
+func TestProbeSpecGetCheck(t *testing.T) {
+	t.Run("empty_exec", func(t *testing.T) {
+		spec := NewProbeSpec()
+		spec.Exec = &[]string{}
+		_, err := spec.check(&mockContainer{})
+		require.EqualError(t, err, "exec action has no command")
+
+		success, err := spec.GetCheck(&mockContainer{}).Run(context.Background())
+		require.False(t, success)
+		require.EqualError(t, err, "exec action has no command")
+	})
+	t.Run("no_action", func(t *testing.T) {
+		success, err := NewProbeSpec().GetCheck(&mockContainer{}).Run(context.Background())
+		require.True(t, success)
+		require.NoError(t, err)
+	})
+}
+
+func TestProbeSpecGetBaseProbe(t *testing.T) {
+	base := ProbeSpec{InitialDelaySeconds: 3, FailureThreshold: 2}.GetBaseProbe()
+	require.Equal(t, 3*time.Second, base.InitialDelay)
+	require.Equal(t, 5*time.Second, base.Period)
+	require.Equal(t, 1*time.Second, base.Timeout)
+	require.Equal(t, 1, base.SuccessThreshold)
+	require.Equal(t, 2, base.FailureThreshold)
+}
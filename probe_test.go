@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"context"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -25,9 +27,13 @@ func newMockCheck(clock clock.Clock, duration time.Duration, success bool, err e
 	}
 }
 
-func (c mockCheck) Run() (bool, error) {
-	c.Clock.Sleep(c.Duration)
-	return c.Success, c.Err
+func (c mockCheck) Run(ctx context.Context) (bool, error) {
+	select {
+	case <-c.Clock.After(c.Duration):
+		return c.Success, c.Err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
 }
 
 type mockMultiCheck struct {
@@ -47,13 +53,13 @@ func (c *mockMultiCheck) Add(check Check) *mockMultiCheck {
 	return c
 }
 
-func (c *mockMultiCheck) Run() (bool, error) {
+func (c *mockMultiCheck) Run(ctx context.Context) (bool, error) {
 	if c.index >= len(c.checks) {
 		panic("mockMultiCheck ran out of checks")
 	}
 	check := c.checks[c.index]
 	defer func() { c.index += 1 }()
-	return check.Run()
+	return check.Run(ctx)
 }
 
 func gosched() {
@@ -109,7 +115,121 @@ func TestLongLivedProbe(t *testing.T) {
 
 		healthy, err := probe.Healthy()
 		require.False(t, healthy)
-		require.NoError(t, err)
+		require.EqualError(t, err, "probe timed out after 1s")
+	})
+	t.Run("timeout_cancels_check", func(t *testing.T) {
+		clock := clock.NewMock()
+		cancelled := make(chan struct{})
+		check := CheckFunc(func(ctx context.Context) (bool, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return false, ctx.Err()
+		})
+
+		probe := newLongLivedProbe(check)
+		probe.InitialDelay = 0 * time.Second
+		probe.Timeout = 1 * time.Second
+		probe.Clock = clock
+
+		probe.Start()
+		gosched()
+
+		timeTravel(clock, 1, time.Second)
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("check was not cancelled after it timed out")
+		}
+		healthy, err := probe.Healthy()
+		require.False(t, healthy)
+		require.EqualError(t, err, "probe timed out after 1s")
+	})
+	t.Run("timeout_waits_for_check", func(t *testing.T) {
+		clock := clock.NewMock()
+		var lock sync.Mutex
+		runs, running, overlapped := 0, 0, false
+		release := make(chan struct{})
+		check := CheckFunc(func(ctx context.Context) (bool, error) {
+			lock.Lock()
+			runs++
+			running++
+			overlapped = overlapped || running > 1
+			first := runs == 1
+			lock.Unlock()
+			defer func() {
+				lock.Lock()
+				running--
+				lock.Unlock()
+			}()
+
+			// The first check does not return when it gets cancelled.
+			if first {
+				<-release
+			}
+			return true, nil
+		})
+
+		probe := newLongLivedProbe(check)
+		probe.InitialDelay = 0 * time.Second
+		probe.Period = 1 * time.Second
+		probe.Timeout = 1 * time.Second
+		probe.FailureThreshold = 10
+		probe.Clock = clock
+
+		probe.Start()
+		gosched()
+		timeTravel(clock, 5, time.Second)
+
+		lock.Lock()
+		require.Equal(t, 1, runs)
+		lock.Unlock()
+
+		close(release)
+		gosched()
+		timeTravel(clock, 2, time.Second)
+		probe.Stop()
+		<-probe.Done()
+
+		lock.Lock()
+		defer lock.Unlock()
+		require.True(t, runs > 1)
+		require.False(t, overlapped)
+	})
+	t.Run("timeout_counts_while_check_runs", func(t *testing.T) {
+		clock := clock.NewMock()
+		var lock sync.Mutex
+		runs := 0
+		release := make(chan struct{})
+		defer close(release)
+		check := CheckFunc(func(ctx context.Context) (bool, error) {
+			lock.Lock()
+			runs++
+			lock.Unlock()
+			<-release
+			return true, nil
+		})
+
+		probe := newLongLivedProbe(check)
+		probe.InitialDelay = 0 * time.Second
+		probe.Period = 1 * time.Second
+		probe.Timeout = 1 * time.Second
+		probe.FailureThreshold = 3
+		probe.Clock = clock
+
+		probe.Start()
+		gosched()
+		timeTravel(clock, 10, time.Second)
+
+		// The check never returns, yet every period still counts as a failure.
+		healthy, err := probe.Healthy()
+		require.False(t, healthy)
+		require.EqualError(t, err, "probe timed out after 1s")
+		require.False(t, probe.Running())
+
+		lock.Lock()
+		defer lock.Unlock()
+		require.Equal(t, 1, runs)
 	})
 	t.Run("healthy_check", func(t *testing.T) {
 		clock := clock.NewMock()
@@ -206,5 +326,11 @@ func TestLongLivedProbe(t *testing.T) {
 
 		running := probe.Running()
 		require.False(t, running)
+
+		select {
+		case <-probe.Done():
+		case <-time.After(time.Second):
+			t.Fatal("probe goroutine did not return after stop")
+		}
 	})
 }
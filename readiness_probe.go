@@ -6,6 +6,8 @@ import (
 	"github.com/benbjohnson/clock"
 )
 
+// NewReadinessProbe returns a probe that keeps running after it fails, like in Kubernetes,
+// so that the container becomes ready again once its check passes.
 func NewReadinessProbe(check Check) *LongLivedProbe {
 	return &LongLivedProbe{
 		// TODO: Check defaults
@@ -19,4 +19,32 @@ func TestReadinessProbe(t *testing.T) {
 		require.False(t, healthy)
 		require.NoError(t, err)
 	})
+	t.Run("recovers_after_failure", func(t *testing.T) {
+		clock := clock.NewMock()
+		result := func(success bool) Check {
+			return newMockCheck(clock, 0*time.Second, success, nil)
+		}
+		multicheck := newMockMultiCheck().Add(result(true)).Add(result(false)).Add(result(true))
+
+		probe := NewReadinessProbe(multicheck)
+		probe.InitialDelay = 0
+		probe.Period = 1 * time.Second
+		probe.Clock = clock
+
+		probe.Start()
+		gosched()
+		timeTravel(clock, 1, 0)
+		healthy, _ := probe.Healthy()
+		require.True(t, healthy)
+
+		timeTravel(clock, 1, time.Second)
+		healthy, _ = probe.Healthy()
+		require.False(t, healthy)
+		require.True(t, probe.Running())
+
+		timeTravel(clock, 1, time.Second)
+		healthy, _ = probe.Healthy()
+		require.True(t, healthy)
+		probe.Stop()
+	})
 }
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+)
+
+// RestartPolicy describes what the controller does with a container once it has
+// exited. It mirrors the restartPolicy of a Kubernetes pod, but is set per container
+// so that restarting one container never affects its siblings.
+type RestartPolicy string
+
+const (
+	RestartAlways    RestartPolicy = "Always"
+	RestartOnFailure RestartPolicy = "OnFailure"
+	RestartNever     RestartPolicy = "Never"
+)
+
+// ShouldRestart returns true if a container that just reached the given state
+// must be relaunched. An empty policy behaves like Never.
+func (policy RestartPolicy) ShouldRestart(state ContainerState) bool {
+	switch policy {
+	case RestartAlways:
+		return state == Finished || state == Failed
+	case RestartOnFailure:
+		return state == Failed
+	}
+	return false
+}
+
+func (policy RestartPolicy) validate() error {
+	switch policy {
+	case "", RestartAlways, RestartOnFailure, RestartNever:
+		return nil
+	}
+	return fmt.Errorf("unknown restart policy %q", policy)
+}
+
+// BackoffSpec holds the settings of the exponential backoff applied between two
+// restarts of the same container. The delay starts at InitialDelaySeconds and doubles
+// on every restart up to MaxDelaySeconds. It goes back to its initial value once the
+// container has stayed up for ResetSeconds. A container that exits again after having
+// been restarted CrashLoopThreshold times within CrashLoopWindowSeconds is in a crash
+// loop. Fields left to 0 take their default value.
+type BackoffSpec struct {
+	InitialDelaySeconds    int
+	MaxDelaySeconds        int
+	ResetSeconds           int
+	CrashLoopThreshold     int
+	CrashLoopWindowSeconds int
+}
+
+func NewBackoffSpec() BackoffSpec {
+	return BackoffSpec{
+		InitialDelaySeconds:    10,
+		MaxDelaySeconds:        300,
+		ResetSeconds:           600,
+		CrashLoopThreshold:     5,
+		CrashLoopWindowSeconds: 600,
+	}
+}
+
+// Next computes the delay to wait before relaunching a container, given the delay
+// used for its previous restart and how long it stayed up this time.
+func (spec BackoffSpec) Next(previous, uptime time.Duration) time.Duration {
+	defaults := NewBackoffSpec()
+	initial := secondsOr(spec.InitialDelaySeconds, defaults.InitialDelaySeconds)
+	max := secondsOr(spec.MaxDelaySeconds, defaults.MaxDelaySeconds)
+	reset := secondsOr(spec.ResetSeconds, defaults.ResetSeconds)
+
+	if previous == 0 || uptime >= reset {
+		return initial
+	} else if next := 2 * previous; next < max {
+		return next
+	}
+	return max
+}
+
+// CrashLooping returns true if a container that just exited is in a crash loop, given
+// the number of times it was restarted within the crash loop window.
+func (spec BackoffSpec) CrashLooping(restarts int) bool {
+	threshold := spec.CrashLoopThreshold
+	if threshold <= 0 {
+		threshold = NewBackoffSpec().CrashLoopThreshold
+	}
+	return restarts >= threshold
+}
+
+// CrashLoopWindow returns the window within which the restarts of a container count
+// towards a crash loop.
+func (spec BackoffSpec) CrashLoopWindow() time.Duration {
+	return secondsOr(spec.CrashLoopWindowSeconds, NewBackoffSpec().CrashLoopWindowSeconds)
+}
+
+func secondsOr(seconds, fallback int) time.Duration {
+	if seconds <= 0 {
+		seconds = fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestartPolicy(t *testing.T) {
+	t.Run("always", func(t *testing.T) {
+		require.True(t, RestartAlways.ShouldRestart(Finished))
+		require.True(t, RestartAlways.ShouldRestart(Failed))
+		require.False(t, RestartAlways.ShouldRestart(Healthy))
+	})
+	t.Run("on_failure", func(t *testing.T) {
+		require.False(t, RestartOnFailure.ShouldRestart(Finished))
+		require.True(t, RestartOnFailure.ShouldRestart(Failed))
+	})
+	t.Run("never", func(t *testing.T) {
+		require.False(t, RestartNever.ShouldRestart(Finished))
+		require.False(t, RestartNever.ShouldRestart(Failed))
+	})
+	t.Run("empty_is_never", func(t *testing.T) {
+		var policy RestartPolicy
+		require.False(t, policy.ShouldRestart(Failed))
+	})
+}
+
+func TestBackoffSpec(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		spec := BackoffSpec{}
+		require.Equal(t, 10*time.Second, spec.Next(0, 0))
+		require.Equal(t, 300*time.Second, spec.Next(200*time.Second, 0))
+	})
+	t.Run("exponential", func(t *testing.T) {
+		spec := BackoffSpec{InitialDelaySeconds: 1, MaxDelaySeconds: 5, ResetSeconds: 60}
+		delay := spec.Next(0, 0)
+		require.Equal(t, 1*time.Second, delay)
+		delay = spec.Next(delay, time.Second)
+		require.Equal(t, 2*time.Second, delay)
+		delay = spec.Next(delay, time.Second)
+		require.Equal(t, 4*time.Second, delay)
+		delay = spec.Next(delay, time.Second)
+		require.Equal(t, 5*time.Second, delay)
+	})
+	t.Run("reset_after_stable_period", func(t *testing.T) {
+		spec := BackoffSpec{InitialDelaySeconds: 1, MaxDelaySeconds: 5, ResetSeconds: 60}
+		require.Equal(t, 1*time.Second, spec.Next(4*time.Second, 60*time.Second))
+	})
+	t.Run("crash_looping", func(t *testing.T) {
+		spec := BackoffSpec{}
+		require.False(t, spec.CrashLooping(4))
+		require.True(t, spec.CrashLooping(5))
+		require.Equal(t, 600*time.Second, spec.CrashLoopWindow())
+
+		spec = BackoffSpec{CrashLoopThreshold: 2, CrashLoopWindowSeconds: 30}
+		require.True(t, spec.CrashLooping(2))
+		require.Equal(t, 30*time.Second, spec.CrashLoopWindow())
+	})
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"plugin"
 
@@ -25,6 +26,36 @@ type Container interface {
 	Exec(program string, arguments ...string) (int, error)
 }
 
+// A ContextExecer is a Container that can kill the processes it execs. The runtime should
+// implement it whenever it can, so that exec probes and hooks that time out do not leave
+// their processes behind.
+type ContextExecer interface {
+	ExecContext(ctx context.Context, program string, arguments ...string) (int, error)
+}
+
+// execContext execs the program in the container. The program gets killed when the context
+// is done if the container is a ContextExecer, otherwise it is left to run in the background.
+func execContext(ctx context.Context, ctn Container, program string, arguments ...string) (int, error) {
+	if execer, ok := ctn.(ContextExecer); ok {
+		return execer.ExecContext(ctx, program, arguments...)
+	}
+	type result struct {
+		code int
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		code, err := ctn.Exec(program, arguments...)
+		done <- result{code, err}
+	}()
+	select {
+	case res := <-done:
+		return res.code, res.err
+	case <-ctx.Done():
+		return 1, errors.WithStack(ctx.Err())
+	}
+}
+
 type RuntimeStrategy struct {
 	Bootstrapper ContainerBootstrapper
 }
@@ -1,9 +1,11 @@
 package controller_test
 
 import (
+	"net"
 	"testing"
 
 	"github.com/apourchet/pod-controller"
+	oci "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -17,4 +19,18 @@ func TestLoadPlugin(t *testing.T) {
 		_, err := controller.LoadPlugin("./bins/does_not_exist")
 		require.Error(t, err)
 	})
+	t.Run("shellout_addresser", func(t *testing.T) {
+		strat, err := controller.LoadPlugin("./bins/shellout.so")
+		require.NoError(t, err)
+		ctn, err := strat.Bootstrapper(oci.Spec{Process: &oci.Process{Args: []string{"true"}}}, nil)
+		require.NoError(t, err)
+
+		addresser, ok := ctn.(controller.Addresser)
+		require.True(t, ok)
+		ips, err := addresser.IPs()
+		require.NoError(t, err)
+		require.Len(t, ips, 1)
+		require.True(t, ips[0].Equal(net.IPv4(127, 0, 0, 1)))
+		require.Nil(t, addresser.Dialer())
+	})
 }
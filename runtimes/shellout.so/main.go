@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"syscall"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+type container struct {
+	cmd *exec.Cmd
+}
+
+func (ctn *container) Start() error { return ctn.cmd.Start() }
+
+func (ctn *container) Wait() error { return ctn.cmd.Wait() }
+
+func (ctn *container) Kill(signal int) error {
+	return ctn.cmd.Process.Signal(syscall.Signal(signal))
+}
+
+func (ctn *container) Exec(program string, arguments ...string) (code int, err error) {
+	return ctn.ExecContext(context.Background(), program, arguments...)
+}
+
+// ExecContext kills the command if the context is done before it exits.
+func (ctn *container) ExecContext(ctx context.Context, program string, arguments ...string) (code int, err error) {
+	command := exec.CommandContext(ctx, program, arguments...)
+	err = command.Run()
+	if err == nil {
+		return 0, nil
+	} else if exiterr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus(), err
+		}
+	}
+	return 1, err
+}
+
+// IPs returns localhost, the commands share the network of the host.
+func (ctn *container) IPs() ([]net.IP, error) {
+	return []net.IP{net.IPv4(127, 0, 0, 1)}, nil
+}
+
+// Dialer returns nil, the probes can connect to the commands from the host.
+func (ctn *container) Dialer() func(ctx context.Context, network, address string) (net.Conn, error) {
+	return nil
+}
+
+// Bootstrapper only looks at the args, its as simple as it gets and does
+// almost nothing with the rest of the oci spec.
+var Bootstrapper = func(spec oci.Spec, meta map[string]interface{}) (interface{}, error) {
+	command := exec.Command(spec.Process.Args[0], spec.Process.Args[1:]...)
+	return &container{cmd: command}, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+type container struct {
+	program   string
+	arguments []string
+	waitChan  chan int
+
+	killOnce sync.Once
+	killChan chan struct{}
+}
+
+func (ctn *container) Start() error {
+	switch ctn.program {
+	case "true":
+		return nil
+	case "false":
+		return nil
+	case "sleep":
+		go func() {
+			durationStr := "0"
+			if len(ctn.arguments) > 0 {
+				durationStr = ctn.arguments[0]
+			}
+			duration, _ := strconv.Atoi(durationStr)
+			select {
+			case <-time.After(time.Duration(duration) * time.Millisecond):
+				ctn.waitChan <- 0
+			case <-ctn.killChan:
+				ctn.waitChan <- 1
+			}
+		}()
+		return nil
+	}
+	return nil
+}
+
+func (ctn *container) Wait() error {
+	switch ctn.program {
+	case "true":
+		return nil
+	case "false":
+		return fmt.Errorf("command `false` failed")
+	case "sleep":
+		if code := <-ctn.waitChan; code != 0 {
+			return fmt.Errorf("command `sleep` was killed")
+		}
+		return nil
+	}
+	return nil
+}
+
+// Kill interrupts the sleep commands, whatever the signal.
+func (ctn *container) Kill(signal int) error {
+	ctn.killOnce.Do(func() { close(ctn.killChan) })
+	return nil
+}
+
+// Exec just executes the command on the host.
+func (ctn *container) Exec(program string, arguments ...string) (code int, err error) {
+	return ctn.ExecContext(context.Background(), program, arguments...)
+}
+
+// ExecContext kills the command if the context is done before it exits.
+func (ctn *container) ExecContext(ctx context.Context, program string, arguments ...string) (code int, err error) {
+	newctn := &container{
+		program:   program,
+		arguments: arguments,
+		waitChan:  make(chan int, 0),
+		killChan:  make(chan struct{}),
+	}
+	if err := newctn.Start(); err != nil {
+		return 1, err
+	}
+
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			newctn.Kill(0)
+		case <-exited:
+		}
+	}()
+	if err := newctn.Wait(); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+// Bootstrapper only looks at the args, its as simple as it gets and does
+// almost nothing with the rest of the oci spec.
+var Bootstrapper = func(spec oci.Spec, _ map[string]interface{}) (interface{}, error) {
+	return &container{
+		program:   spec.Process.Args[0],
+		arguments: spec.Process.Args[1:],
+		waitChan:  make(chan int, 0),
+		killChan:  make(chan struct{}),
+	}, nil
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ContainerRole tells the controller whether a container is part of the work of the pod
+// or only supports it. Sidecars are started before the main containers and get terminated
+// once all of the main containers have exited for good, so they never hold the pod open.
+// The main containers implicitly depend on every sidecar being started.
+type ContainerRole string
+
+const (
+	RoleMain    ContainerRole = "main"
+	RoleSidecar ContainerRole = "sidecar"
+)
+
+func (role ContainerRole) validate() error {
+	switch role {
+	case "", RoleMain, RoleSidecar:
+		return nil
+	}
+	return fmt.Errorf("unknown container role %q", role)
+}
+
+func (spec ContainerSpec) isSidecar() bool {
+	return spec.Role == RoleSidecar
+}
+
+// terminateSidecars terminates the sidecars of the pod once all of its main containers
+// have exited for good. It only does so once, in the background.
+func (c *controller) terminateSidecars() {
+	phase := c.containersPhase()
+	if phase != PodSucceeded && phase != PodFailed {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if c.sidecarsTerminated {
+		return
+	}
+	c.sidecarsTerminated = true
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ctx, cancel := c.stopContext()
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for _, name := range c.MainOrder {
+			if !c.getInfo(name).spec.isSidecar() {
+				continue
+			}
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				c.terminate(ctx, name)
+			}(name)
+		}
+		wg.Wait()
+	}()
+}
+
+// stopContext returns a context that gets cancelled when the controller is stopped.
+func (c *controller) stopContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-c.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
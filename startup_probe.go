@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// NewStartupProbe returns a probe that stops as soon as its check succeeds. It is
+// unhealthy until then, and gives up after FailureThreshold consecutive failures.
+func NewStartupProbe(check Check) *LongLivedProbe {
+	return &LongLivedProbe{
+		BaseProbe: BaseProbe{
+			InitialDelay:     0,
+			Period:           10 * time.Second,
+			Timeout:          1 * time.Second,
+			SuccessThreshold: 1,
+			FailureThreshold: 3,
+		},
+		Check:         check,
+		Clock:         clock.New(),
+		StopOnSuccess: true,
+		StopOnFailure: true,
+	}
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupProbe(t *testing.T) {
+	t.Run("stops_on_success", func(t *testing.T) {
+		clock := clock.NewMock()
+		failure := newMockCheck(clock, 0*time.Second, false, nil)
+		success := newMockCheck(clock, 0*time.Second, true, nil)
+		multicheck := newMockMultiCheck().Add(failure).Add(success)
+
+		probe := NewStartupProbe(multicheck)
+		probe.Period = 2 * time.Second
+		probe.Clock = clock
+
+		probe.Start()
+		gosched()
+		healthy, _ := probe.Healthy()
+		require.False(t, healthy)
+		require.True(t, probe.Running())
+
+		timeTravel(clock, 2, time.Second)
+		healthy, err := probe.Healthy()
+		require.True(t, healthy)
+		require.NoError(t, err)
+		require.False(t, probe.Running())
+		<-probe.Done()
+	})
+	t.Run("gives_up", func(t *testing.T) {
+		clock := clock.NewMock()
+		failure := newMockCheck(clock, 0*time.Second, false, nil)
+		multicheck := newMockMultiCheck().Add(failure).Add(failure)
+
+		probe := NewStartupProbe(multicheck)
+		probe.Period = 2 * time.Second
+		probe.FailureThreshold = 2
+		probe.Clock = clock
+
+		probe.Start()
+		gosched()
+		timeTravel(clock, 2, time.Second)
+
+		healthy, _ := probe.Healthy()
+		require.False(t, healthy)
+		require.False(t, probe.Running())
+	})
+	t.Run("spec_defaults", func(t *testing.T) {
+		spec := StartupProbeSpec{ProbeSpec{PeriodSeconds: 2}}
+		spec.Exec = &[]string{"true"}
+		probe, err := spec.Materialize(&mockContainer{})
+		require.NoError(t, err)
+
+		startup := probe.(*LongLivedProbe)
+		require.Equal(t, 3, startup.FailureThreshold)
+		require.Equal(t, 1, startup.SuccessThreshold)
+		require.Equal(t, 2*time.Second, startup.Period)
+		require.Equal(t, 1*time.Second, startup.Timeout)
+		require.True(t, startup.StopOnSuccess)
+	})
+	t.Run("no_action", func(t *testing.T) {
+		probe, err := StartupProbeSpec{NewProbeSpec()}.Materialize(&mockContainer{})
+		require.NoError(t, err)
+		probe.Start()
+		healthy, err := probe.Healthy()
+		require.True(t, healthy)
+		require.NoError(t, err)
+		<-probe.Done()
+	})
+}
@@ -0,0 +1,280 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultStopSignal  = int(syscall.SIGTERM)
+	defaultGracePeriod = 30 * time.Second
+)
+
+// Termination records how the controller brought a container down. Killed is set if
+// the container had to be sent SIGKILL after its grace period ran out. Tier is the
+// position of the container in the shutdown order of the pod, and TierDuration the
+// time it took for its whole tier to exit.
+type Termination struct {
+	Signal       int
+	Killed       bool
+	Exited       bool
+	Duration     time.Duration
+	Error        string
+	Timestamp    time.Time
+	Tier         int
+	TierDuration time.Duration
+}
+
+func (spec ContainerSpec) stopSignal() int {
+	if spec.StopSignal == 0 {
+		return defaultStopSignal
+	}
+	return spec.StopSignal
+}
+
+func (spec ContainerSpec) gracePeriod() time.Duration {
+	if spec.TerminationGracePeriodSeconds == nil {
+		return defaultGracePeriod
+	}
+	return time.Duration(*spec.TerminationGracePeriodSeconds) * time.Second
+}
+
+// ReasonTerminated is the reason of a pod that got terminated before its main containers
+// were launched, or while they were brought down to restart the pod.
+const ReasonTerminated = "Terminated"
+
+// Terminate sends the stop signal of each container and waits for them to exit. The
+// containers still running after their grace period are killed with SIGKILL, as are
+// all of the remaining ones if the context expires. The containers are terminated tier
+// by tier following the shutdown order of the pod, each tier waiting for the previous
+// one to exit. Once Terminate has been called the controller stops restarting containers,
+// and kills the init container that is running if any.
+func (c *controller) Terminate(ctx context.Context) error {
+	c.Lock()
+	if !c.terminating {
+		close(c.interrupted)
+	}
+	c.terminating = true
+	c.Unlock()
+
+	errs := []error{}
+	for i, tier := range c.shutdownTiers() {
+		start := c.Clock.Now()
+		errs = append(errs, c.terminateAll(ctx, tier)...)
+		duration := c.Clock.Now().Sub(start)
+		for _, name := range tier {
+			c.getInfo(name).status.RecordShutdownTier(i, duration)
+		}
+	}
+
+	errs = filterErrors(errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to terminate pod: %v", stringifyErrors(errs))
+	}
+	return nil
+}
+
+// abandon fails the pod when it got terminated before its main containers could be
+// launched or relaunched, so that Phase and Wait do not wait for containers that will
+// never run. A pod that already has a reason, like an exceeded deadline, gets completed
+// by whatever set it.
+func (c *controller) abandon() {
+	c.Lock()
+	abandoned := c.reason == ""
+	if abandoned {
+		c.reason = ReasonTerminated
+	}
+	c.Unlock()
+	if abandoned {
+		c.complete(PodResult{Phase: PodFailed, Reason: ReasonTerminated, ExitCode: 1})
+	}
+}
+
+// terminateAll terminates the containers concurrently and waits for all of them.
+func (c *controller) terminateAll(ctx context.Context, names []string) []error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(names))
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			errs[i] = c.terminate(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+	return errs
+}
+
+// shutdownTiers groups the containers in the order in which they get terminated. It
+// follows the shutdownOrder of the pod spec if there is one, with the containers it
+// does not list making up a last tier. Otherwise it is the reverse of the startup
+// order: a container is terminated before all of the containers it depends on.
+func (c *controller) shutdownTiers() [][]string {
+	if len(c.shutdownOrder) > 0 {
+		tiers, listed := [][]string{}, map[string]bool{}
+		for _, tier := range c.shutdownOrder {
+			tiers = append(tiers, tier)
+			for _, name := range tier {
+				listed[name] = true
+			}
+		}
+		rest := []string{}
+		for _, name := range c.MainOrder {
+			if !listed[name] {
+				rest = append(rest, name)
+			}
+		}
+		if len(rest) > 0 {
+			tiers = append(tiers, rest)
+		}
+		return tiers
+	}
+
+	// The depth of a container is the length of its longest chain of dependencies, the
+	// deepest containers are the last ones to start and the first ones to stop.
+	depths := map[string]int{}
+	var depth func(name string) int
+	depth = func(name string) int {
+		if d, ok := depths[name]; ok {
+			return d
+		}
+		d := 0
+		for _, dep := range c.dependencies(name) {
+			if dd := depth(dep.Name) + 1; dd > d {
+				d = dd
+			}
+		}
+		depths[name] = d
+		return d
+	}
+	max := 0
+	for _, name := range c.MainOrder {
+		if d := depth(name); d > max {
+			max = d
+		}
+	}
+
+	tiers := make([][]string, max+1)
+	for _, name := range c.MainOrder {
+		tier := max - depths[name]
+		tiers[tier] = append(tiers[tier], name)
+	}
+	return tiers
+}
+
+// validateShutdownOrder makes sure that the shutdown order only lists known containers,
+// and lists them once.
+func (c *controller) validateShutdownOrder() error {
+	listed := map[string]bool{}
+	for _, tier := range c.shutdownOrder {
+		for _, name := range tier {
+			if _, ok := c.MainInfos[name]; !ok {
+				return fmt.Errorf("shutdown order lists unknown container %s", name)
+			} else if listed[name] {
+				return fmt.Errorf("shutdown order lists container %s more than once", name)
+			}
+			listed[name] = true
+		}
+	}
+	return nil
+}
+
+// terminate brings down a single container and records the outcome in its status. A
+// container only gets terminated once, a concurrent call waits for the termination that
+// is already in progress instead, so that its preStop hook and signals are not sent twice.
+func (c *controller) terminate(ctx context.Context, name string) error {
+	c.Lock()
+	done, terminating := c.terminated[name]
+	if !terminating {
+		done = make(chan struct{})
+		c.terminated[name] = done
+	}
+	c.Unlock()
+	if terminating {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "container %s did not exit", name)
+		}
+	}
+	defer close(done)
+
+	info := c.getInfo(name)
+	info.status.CancelRestart()
+	exit := info.probes.Exit
+	if !exit.Running() {
+		info.status.RecordTermination(&Termination{Exited: true, Timestamp: c.Clock.Now()})
+		return nil
+	}
+
+	start := c.Clock.Now()
+	termination := &Termination{Signal: info.spec.stopSignal()}
+	err := c.signal(ctx, info, info.spec.gracePeriod(), termination)
+	if err != nil {
+		termination.Error = err.Error()
+		info.status.AddError(&ProbeError{Message: err.Error(), Timestamp: c.Clock.Now()})
+	}
+	termination.Exited = !exit.Running()
+	termination.Duration = c.Clock.Now().Sub(start)
+	termination.Timestamp = c.Clock.Now()
+	info.status.RecordTermination(termination)
+	return err
+}
+
+// signal runs the preStop hook of the container and sends it the stop signal, escalating
+// to SIGKILL if it did not exit within the grace period.
+func (c *controller) signal(ctx context.Context, info ContainerInfo, grace time.Duration, termination *Termination) error {
+	name, exited := info.spec.Name, info.probes.Exit.Done()
+	if grace > 0 {
+		grace -= c.preStop(info, grace, ctx.Done())
+		select {
+		case <-exited:
+			termination.Signal = 0
+			return nil
+		default:
+		}
+	}
+	if grace > 0 {
+		if err := info.ctn.Kill(termination.Signal); err != nil {
+			// The container may have exited right before it got the signal.
+			select {
+			case <-exited:
+				termination.Signal = 0
+				return nil
+			default:
+			}
+			return errors.Wrapf(err, "failed to send signal %d to container %s", termination.Signal, name)
+		}
+		select {
+		case <-exited:
+			return nil
+		case <-c.Clock.After(grace):
+		case <-ctx.Done():
+		}
+	}
+
+	if grace <= 0 {
+		termination.Signal = int(syscall.SIGKILL)
+	}
+	termination.Killed = true
+	if err := info.ctn.Kill(int(syscall.SIGKILL)); err != nil {
+		select {
+		case <-exited:
+			return nil
+		default:
+		}
+		return errors.Wrapf(err, "failed to kill container %s", name)
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "container %s did not exit", name)
+	}
+}
@@ -9,6 +9,15 @@ func intsContain(arr []int, needle int) bool {
 	return false
 }
 
+func stringsContain(arr []string, needle string) bool {
+	for _, s := range arr {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
 func filterErrors(errs []error) []error {
 	filtered := []error{}
 	for _, err := range errs {
@@ -28,3 +37,9 @@ func stringifyErrors(errs []error) []string {
 	}
 	return out
 }
+
+func closedChan() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}